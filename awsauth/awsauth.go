@@ -0,0 +1,146 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+// Package awsauth obtains credentials for a profile from the AWS config
+// files, following the same profile chains as the aws-auth command, for use
+// by other Go programs. Credentials can be obtained directly with Resolve, or
+// through a credentials provider for the AWS SDK for Go, which refreshes them
+// as they expire.
+package awsauth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/httpclient"
+	"github.com/joshdk/aws-auth/mfa"
+	"github.com/joshdk/aws-auth/transformers"
+)
+
+// Options configure how credentials are obtained. The zero value is usable,
+// and behaves like the aws-auth command.
+type Options struct {
+	// Config is the AWS config that profiles are found in. If nil, the AWS
+	// config files are loaded, as found from the environment.
+	Config *config.Config
+
+	// MFA provides MFA codes for the given MFA device. The terminal is
+	// never prompted, so obtaining credentials for a profile that requires
+	// MFA fails if this is nil.
+	MFA func(ctx context.Context, serial string) (string, error)
+
+	// HTTPClient is used for every request. If nil, a shared client is
+	// used, which trusts any configured ca_bundle.
+	HTTPClient *http.Client
+
+	// Notices, if set, receives notices about how credentials were
+	// obtained, like a session duration being shortened.
+	Notices io.Writer
+
+	// ExpiryWindow is how long before credentials expire that a provider
+	// treats them as expired, and refreshes them. It defaults to 5 minutes.
+	ExpiryWindow time.Duration
+}
+
+// Credentials are the credentials obtained for a profile.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Expiration is when the credentials expire, or the zero time.Time if
+	// they never do, like the long-lived access keys of a user.
+	Expiration time.Time
+}
+
+// Resolve obtains credentials for the named profile, making every transform
+// in its profile chain. Profiles that log in with SAML must set
+// saml_assertion_file or saml_assertion_command, and role_arn, as the
+// library never opens a browser or asks which role to use. Errors can be
+// inspected with transformers.ExitCode and transformers.Hint.
+func Resolve(ctx context.Context, profile string, opts Options) (*Credentials, error) {
+	cfg := opts.Config
+	if cfg == nil {
+		var err error
+		if cfg, err = config.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Never fall back to prompting on the terminal, which would block a
+	// program that isn't attached to one.
+	prompter := opts.MFA
+	if prompter == nil {
+		prompter = noMFA
+	}
+	ctx = mfa.WithPrompter(ctx, prompter)
+
+	if opts.Notices != nil {
+		ctx = transformers.WithNotices(ctx, opts.Notices)
+	}
+
+	if opts.HTTPClient != nil {
+		ctx = httpclient.WithClient(ctx, httpclient.Static(opts.HTTPClient))
+	}
+
+	// Find a chain of transforms for obtaining profile credentials.
+	startCreds, transforms, err := transformers.Chain(cfg, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	// SAML logins happen in a browser, and roles are chosen on the terminal,
+	// neither of which a program can do on the user's behalf.
+	if err := checkSAML(transforms); err != nil {
+		return nil, err
+	}
+
+	// Make all of the transforms needed to obtain those credentials.
+	endCreds, err := transformers.Transform(ctx, startCreds, transforms)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credentials{
+		AccessKeyID:     aws.StringValue(endCreds.AccessKeyId),
+		SecretAccessKey: aws.StringValue(endCreds.SecretAccessKey),
+		SessionToken:    aws.StringValue(endCreds.SessionToken),
+		Expiration:      aws.TimeValue(endCreds.Expiration),
+	}, nil
+}
+
+// noMFA is used in place of a missing Options.MFA callback, failing rather
+// than prompting on the terminal.
+func noMFA(_ context.Context, serial string) (string, error) {
+	return "", &transformers.Error{
+		Kind: transformers.KindMFA,
+		Hint: "set Options.MFA to provide MFA codes",
+		Err:  fmt.Errorf("MFA required for %s but no Options.MFA callback was given", serial),
+	}
+}
+
+// checkSAML returns an error if any of the given transforms is a SAML login
+// that would open a browser, or prompt for a role on the terminal.
+func checkSAML(transforms []transformers.Transformer) error {
+	for _, transform := range transforms {
+		if _, ok := transform.(transformers.SAMLTransform); !ok {
+			continue
+		}
+
+		if reason := transformers.Interactive([]transformers.Transformer{transform}); reason != "" {
+			return &transformers.Error{
+				Kind: transformers.KindConfig,
+				Hint: "set saml_assertion_file or saml_assertion_command, and role_arn, for profiles used by the library",
+				Err:  fmt.Errorf("%s, which the library does not support", reason),
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package awsauth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/fakests"
+	"github.com/joshdk/aws-auth/transformers"
+)
+
+// setup starts a fake STS, and loads AWS config files with profiles that
+// use it.
+func setup(t *testing.T) *config.Config {
+	server := httptest.NewServer(fakests.New(fakests.Config{
+		Users: []fakests.User{
+			{
+				ARN:             "arn:aws:iam::111111111111:user/alice",
+				AccessKeyID:     "AKIAALICE",
+				SecretAccessKey: "secret",
+				MFASerial:       "arn:aws:iam::111111111111:mfa/alice",
+				MFACode:         "123456",
+			},
+		},
+		Roles: []fakests.Role{
+			{
+				ARN:        "arn:aws:iam::222222222222:role/admin",
+				Trust:      []string{"arn:aws:iam::111111111111:user/alice"},
+				RequireMFA: true,
+			},
+		},
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"config": strings.Join([]string{
+			"[profile admin]",
+			"role_arn = arn:aws:iam::222222222222:role/admin",
+			"source_profile = default",
+			"mfa_serial = arn:aws:iam::111111111111:mfa/alice",
+			"region = us-east-1",
+			"endpoint_url = " + server.URL,
+			"",
+			"[profile browser]",
+			"saml_idp_url = https://idp.example.com/saml",
+			"role_arn = arn:aws:iam::222222222222:role/admin",
+			"principal_arn = arn:aws:iam::222222222222:saml-provider/idp",
+			"",
+			"[profile choose]",
+			"saml_assertion_file = assertion.xml",
+		}, "\n"),
+		"credentials": strings.Join([]string{
+			"[default]",
+			"aws_access_key_id = AKIAALICE",
+			"aws_secret_access_key = secret",
+		}, "\n"),
+	}
+	for name, body := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0600); err != nil {
+			t.Fatalf("expected no error but got error %q", err)
+		}
+	}
+
+	defer setenv(config.EnvVarAWSConfigFile, filepath.Join(dir, "config"))()
+	defer setenv(config.EnvVarAWSSharedCredentialsFile, filepath.Join(dir, "credentials"))()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	return cfg
+}
+
+// setenv sets the given environment variable, and returns a function that
+// restores its previous value.
+func setenv(key, value string) func() {
+	previous, found := os.LookupEnv(key)
+	os.Setenv(key, value)
+
+	return func() {
+		if found {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// code returns an MFA callback that always provides the given code.
+func code(value string) func(context.Context, string) (string, error) {
+	return func(context.Context, string) (string, error) {
+		return value, nil
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cfg := setup(t)
+
+	tests := []struct {
+		profile  string
+		mfa      func(context.Context, string) (string, error)
+		prefix   string
+		expires  bool
+		exitCode int
+	}{
+		{
+			profile: "default",
+			prefix:  "AKIA",
+		},
+		{
+			profile: "admin",
+			mfa:     code("123456"),
+			prefix:  "ASIA",
+			expires: true,
+		},
+		{
+			profile:  "admin",
+			mfa:      code("000000"),
+			exitCode: 5,
+		},
+		{
+			profile:  "admin",
+			exitCode: 5,
+		},
+		{
+			profile:  "missing",
+			exitCode: 3,
+		},
+		{
+			profile:  "browser",
+			exitCode: 2,
+		},
+		{
+			profile:  "choose",
+			exitCode: 2,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			creds, err := Resolve(context.Background(), test.profile, Options{
+				Config: cfg,
+				MFA:    test.mfa,
+			})
+			switch {
+			case err != nil && test.exitCode == 0:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.exitCode != 0:
+				t.Fatalf("expected an error but got no error")
+			case err != nil:
+				if code := transformers.ExitCode(err); code != test.exitCode {
+					t.Fatalf("expected exit code %d but got %d", test.exitCode, code)
+				}
+				return
+			}
+
+			if !strings.HasPrefix(creds.AccessKeyID, test.prefix) {
+				t.Fatalf("expected access key id with prefix %q but got %q", test.prefix, creds.AccessKeyID)
+			}
+
+			if expires := !creds.Expiration.IsZero(); expires != test.expires {
+				t.Fatalf("expected expiring %t but got %t", test.expires, expires)
+			}
+		})
+	}
+}
+
+func TestProviders(t *testing.T) {
+	cfg := setup(t)
+
+	tests := []struct {
+		expiryWindow time.Duration
+		refreshed    bool
+	}{
+		{
+			expiryWindow: 5 * time.Minute,
+		},
+		{
+			// Credentials are obtained for 1 hour, so are always about to
+			// expire.
+			expiryWindow: 2 * time.Hour,
+			refreshed:    true,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			opts := Options{
+				Config:       cfg,
+				MFA:          code("123456"),
+				ExpiryWindow: test.expiryWindow,
+			}
+
+			// Retrieve credentials twice with the v1 provider.
+			v1 := credentials.NewCredentials(NewV1Provider("admin", opts))
+			first, err := v1.Get()
+			if err != nil {
+				t.Fatalf("expected no error but got error %q", err)
+			}
+			second, err := v1.Get()
+			if err != nil {
+				t.Fatalf("expected no error but got error %q", err)
+			}
+
+			if refreshed := first.AccessKeyID != second.AccessKeyID; refreshed != test.refreshed {
+				t.Fatalf("expected refreshed %t but got %t", test.refreshed, refreshed)
+			}
+
+			// Retrieve credentials with the v2 provider.
+			v2, err := NewV2Provider("admin", opts).Retrieve(context.Background())
+			if err != nil {
+				t.Fatalf("expected no error but got error %q", err)
+			}
+
+			if !v2.CanExpire || v2.Expired() != test.refreshed {
+				t.Fatalf("expected expired %t but got %t", test.refreshed, v2.Expired())
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package awsauth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// defaultExpiryWindow is how long before credentials expire that they are
+// refreshed, when not otherwise configured.
+const defaultExpiryWindow = 5 * time.Minute
+
+// providerName is the name that providers report obtained credentials with.
+const providerName = "AWSAuthProvider"
+
+// Provider obtains credentials for a single profile, and keeps them until
+// they are about to expire. It is safe for concurrent use.
+type Provider struct {
+	profile string
+	opts    Options
+
+	lock  sync.Mutex
+	creds *Credentials
+}
+
+// NewProvider creates a Provider for the named profile.
+func NewProvider(profile string, opts Options) *Provider {
+	if opts.ExpiryWindow == 0 {
+		opts.ExpiryWindow = defaultExpiryWindow
+	}
+
+	return &Provider{
+		profile: profile,
+		opts:    opts,
+	}
+}
+
+// Retrieve returns credentials for the profile, obtaining new ones if there
+// are none yet, or if they are about to expire.
+func (p *Provider) Retrieve(ctx context.Context) (*Credentials, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.creds != nil && !p.expired() {
+		return p.creds, nil
+	}
+
+	creds, err := Resolve(ctx, p.profile, p.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p.creds = creds
+	return creds, nil
+}
+
+// IsExpired reports whether the credentials are about to expire, or if there
+// are none yet.
+func (p *Provider) IsExpired() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.creds == nil || p.expired()
+}
+
+// expired reports whether the current credentials are about to expire. The
+// lock must be held.
+func (p *Provider) expired() bool {
+	if p.creds.Expiration.IsZero() {
+		return false
+	}
+	return !time.Now().Add(p.opts.ExpiryWindow).Before(p.creds.Expiration)
+}
+
+// V1Provider adapts a Provider to the credentials.Provider interface of the
+// AWS SDK for Go v1.
+//
+//	sess, err := session.NewSession(&aws.Config{
+//		Credentials: credentials.NewCredentials(awsauth.NewV1Provider("production", opts)),
+//	})
+type V1Provider struct {
+	provider *Provider
+}
+
+var _ credentials.ProviderWithContext = (*V1Provider)(nil)
+
+// NewV1Provider creates a V1Provider for the named profile.
+func NewV1Provider(profile string, opts Options) *V1Provider {
+	return &V1Provider{
+		provider: NewProvider(profile, opts),
+	}
+}
+
+// Retrieve returns credentials for the profile.
+func (v *V1Provider) Retrieve() (credentials.Value, error) {
+	return v.RetrieveWithContext(context.Background())
+}
+
+// RetrieveWithContext returns credentials for the profile, abandoning any API
+// calls if the given context is canceled.
+func (v *V1Provider) RetrieveWithContext(ctx credentials.Context) (credentials.Value, error) {
+	creds, err := v.provider.Retrieve(ctx)
+	if err != nil {
+		return credentials.Value{ProviderName: providerName}, err
+	}
+
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		ProviderName:    providerName,
+	}, nil
+}
+
+// IsExpired reports whether the credentials need to be retrieved again.
+func (v *V1Provider) IsExpired() bool {
+	return v.provider.IsExpired()
+}
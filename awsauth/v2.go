@@ -0,0 +1,56 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package awsauth
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// V2Provider adapts a Provider to the aws.CredentialsProvider interface of
+// the AWS SDK for Go v2.
+//
+//	cfg, err := config.LoadDefaultConfig(ctx,
+//		config.WithCredentialsProvider(awsauth.NewV2Provider("production", opts)),
+//	)
+type V2Provider struct {
+	provider *Provider
+}
+
+var _ aws.CredentialsProvider = (*V2Provider)(nil)
+
+// NewV2Provider creates a V2Provider for the named profile.
+func NewV2Provider(profile string, opts Options) *V2Provider {
+	return &V2Provider{
+		provider: NewProvider(profile, opts),
+	}
+}
+
+// Retrieve returns credentials for the profile, abandoning any API calls if
+// the given context.Context is canceled.
+func (v *V2Provider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, err := v.provider.Retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	result := aws.Credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Source:          providerName,
+	}
+
+	// Credentials are reported as expiring early, so that a
+	// aws.CredentialsCache refreshes them at the same time that the Provider
+	// would.
+	if !creds.Expiration.IsZero() {
+		result.CanExpire = true
+		result.Expires = creds.Expiration.Add(-v.provider.opts.ExpiryWindow)
+	}
+
+	return result, nil
+}
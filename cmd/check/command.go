@@ -0,0 +1,168 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joshdk/aws-auth/cmd/flags"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/transformers"
+	"github.com/spf13/cobra"
+)
+
+// Statuses that a profile can be reported with.
+const (
+	statusOK      = "ok"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+// entry describes whether credentials could be obtained for a single
+// profile.
+type entry struct {
+	Profile string `json:"profile"`
+	Status  string `json:"status"`
+	ARN     string `json:"arn,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// Command defines the aws-auth check command.
+//
+// $ aws-auth check
+func Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check that credentials can be obtained for every profile",
+		Long:  "aws-auth check - Check that credentials can be obtained for every profile",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flagMatch, _ := cmd.Flags().GetStringArray("match")
+			flagOutput, _ := cmd.Flags().GetString("output")
+
+			if flagOutput != "table" && flagOutput != "json" {
+				return fmt.Errorf("unknown output format %q", flagOutput)
+			}
+
+			// Abandon any API calls after the --timeout elapses.
+			ctx, cancel, err := flags.Context(cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			// Load and parse the AWS config files.
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			// Find every profile matching any of the given patterns.
+			profiles, err := cfg.Match(flagMatch...)
+			if err != nil {
+				return err
+			}
+
+			// Find a chain of transforms for obtaining credentials for each
+			// profile. Profiles that would prompt the user are skipped, as
+			// checks must run unattended. Sessions are never cached, so
+			// profiles that require MFA are always skipped.
+			entries := make([]entry, len(profiles))
+			var targets []transformers.Target
+			var indices []int
+			for index, profile := range profiles {
+				entries[index].Profile = profile
+
+				target, err := flags.Target(cmd, cfg, profile)
+				if err != nil {
+					entries[index].fail(err)
+					continue
+				}
+
+				switch reason := transformers.Interactive(target.Transforms); {
+				case reason != "":
+					entries[index].Status = statusSkipped
+					entries[index].Reason = reason
+				default:
+					targets = append(targets, target)
+					indices = append(indices, index)
+				}
+			}
+
+			// Obtain and enrich credentials for every remaining profile.
+			for position, result := range transformers.Resolve(ctx, targets) {
+				index := indices[position]
+				if result.Err != nil {
+					entries[index].fail(result.Err)
+					continue
+				}
+
+				entries[index].Status = statusOK
+				entries[index].ARN = result.Identity.ARN
+			}
+
+			if flagOutput == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(entries); err != nil {
+					return err
+				}
+			} else if err := printTable(entries); err != nil {
+				return err
+			}
+
+			// Fail if credentials could not be obtained for any profile.
+			var failures int
+			for _, entry := range entries {
+				if entry.Status == statusFailed {
+					failures++
+				}
+			}
+			if failures > 0 {
+				return fmt.Errorf("failed to obtain credentials for %d of %d profile(s)", failures, len(entries))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayP("match", "m", []string{"*"}, "glob pattern of profiles to check, may be repeated")
+	cmd.Flags().StringP("output", "o", "table", "output format (table or json)")
+	flags.AddChain(cmd)
+
+	return cmd
+}
+
+// fail marks the entry as failed for the given reason.
+func (e *entry) fail(err error) {
+	e.Status = statusFailed
+	e.Reason = strings.ReplaceAll(err.Error(), "\n", " ")
+	e.Hint = transformers.Hint(err)
+}
+
+// printTable prints the given entries as a table.
+func printTable(entries []entry) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PROFILE\tSTATUS\tDETAIL")
+
+	for _, entry := range entries {
+		detail := entry.ARN
+		if entry.Reason != "" {
+			detail = entry.Reason
+		}
+		if entry.Hint != "" {
+			detail += " (" + entry.Hint + ")"
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", entry.Profile, entry.Status, detail)
+	}
+
+	return writer.Flush()
+}
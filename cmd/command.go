@@ -5,10 +5,22 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
 
+	"github.com/joshdk/aws-auth/cmd/check"
+	configcmd "github.com/joshdk/aws-auth/cmd/config"
 	"github.com/joshdk/aws-auth/cmd/console"
+	"github.com/joshdk/aws-auth/cmd/each"
+	"github.com/joshdk/aws-auth/cmd/explain"
+	"github.com/joshdk/aws-auth/cmd/fakests"
+	"github.com/joshdk/aws-auth/cmd/flags"
+	"github.com/joshdk/aws-auth/cmd/graph"
+	"github.com/joshdk/aws-auth/cmd/profiles"
 	"github.com/joshdk/aws-auth/config"
 	"github.com/joshdk/aws-auth/transformers"
 	"github.com/spf13/cobra"
@@ -27,47 +39,105 @@ func Command(version, date string) *cobra.Command {
 		SilenceErrors: true,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			flagProfile, _ := cmd.Flags().GetString("profile")
+			flagProfiles := flags.Profiles(cmd)
+			flagSourceProfile, _ := cmd.Flags().GetString("source-profile")
+			flagOutput, _ := cmd.Flags().GetString("output")
 
-			// Load and parse the AWS config files.
-			cfg, err := config.Load()
+			// Abandon any API calls or prompts after the --timeout elapses.
+			ctx, cancel, err := flags.Context(cmd)
 			if err != nil {
 				return err
 			}
+			defer cancel()
 
-			// Find a chain of transforms for obtaining profile credentials.
-			startCreds, transforms, err := transformers.Chain(cfg, flagProfile)
-			if err != nil {
-				return err
+			// The --source-profile flag takes the place of --profile, as the
+			// start of an ad-hoc chain.
+			if flagSourceProfile != "" {
+				flagProfiles = []string{flagSourceProfile}
 			}
 
-			// Make all of the transforms needed to obtain those credentials.
-			endCreds, err := transformers.Transform(startCreds, transforms)
-			if err != nil {
-				return err
+			// Environment variables can only describe a single identity.
+			switch {
+			case flagOutput != "env" && flagOutput != "json":
+				return fmt.Errorf("unknown output format %q", flagOutput)
+			case flagOutput == "env" && len(flagProfiles) != 1:
+				return fmt.Errorf("multiple profiles require --output json")
 			}
 
-			// Enrich credentials with identity information.
-			identity, err := transformers.Enrich(endCreds)
+			// Load and parse the AWS config files.
+			cfg, err := config.Load()
 			if err != nil {
 				return err
 			}
 
-			// Print environment variables for our new identity.
-			for key, value := range identity.Env() {
-				fmt.Printf("export %s=%q\n", key, value)
+			// Find a chain of transforms for obtaining credentials for each
+			// profile.
+			targets := make([]transformers.Target, 0, len(flagProfiles))
+			for _, profile := range flagProfiles {
+				target, err := flags.Target(cmd, cfg, profile)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, target)
+			}
+
+			// Make all of the transforms needed to obtain those credentials,
+			// and enrich them with identity information.
+			results := transformers.Resolve(ctx, targets)
+
+			if flagOutput == "json" {
+				if err := printJSON(results); err != nil {
+					return err
+				}
+			} else if results[0].Err == nil {
+				// Print environment variables for our new identity.
+				for key, value := range results[0].Identity.Env() {
+					fmt.Printf("export %s=%q\n", key, value)
+				}
 			}
 
-			return nil
+			// Fail if credentials could not be obtained for any profile,
+			// reporting every profile that failed.
+			var failed failures
+			for _, result := range results {
+				if result.Err != nil {
+					failed = append(failed, result)
+				}
+			}
+
+			switch {
+			case len(failed) == 0:
+				return nil
+			case len(targets) == 1:
+				return failed[0].Err
+			default:
+				return failed
+			}
 		},
 	}
 
 	cmd.SetVersionTemplate(versionTemplate(version, date))
 
-	cmd.PersistentFlags().StringP("profile", "p", "default", "config profile to target")
+	cmd.Flags().StringP("output", "o", "env", "output format (env or json)")
+	cmd.PersistentFlags().StringArrayP("profile", "p", []string{"default"}, "config profile to target, may be repeated with --output json")
+	cmd.PersistentFlags().Duration("timeout", 0, "abandon obtaining credentials after this long (e.g. 30s), 0 for no timeout")
+	cmd.PersistentFlags().Bool("debug-http", false, "dump every HTTP request and response to stderr, with secrets redacted")
+	cmd.PersistentFlags().String("record", "", "record every HTTP request and response to this file, with secrets redacted")
+	cmd.PersistentFlags().String("replay", "", "serve HTTP responses from a file written by --record, instead of making requests")
+	cmd.PersistentFlags().Bool("verbose", false, "trace each step of obtaining credentials to stderr")
+	cmd.PersistentFlags().String("trace", "", "trace each step of obtaining credentials to this JSON file")
+	flags.AddSourceProfile(cmd)
+	flags.AddChain(cmd)
 
 	cmd.AddCommand(
+		check.Command(),
+		configcmd.Command(),
 		console.Command(),
+		each.Command(),
+		explain.Command(),
+		fakests.Command(),
+		graph.Command(),
+		profiles.Command(),
 	)
 
 	return cmd
@@ -76,13 +146,62 @@ func Command(version, date string) *cobra.Command {
 // Execute handles the CLI and runs it to completion. This function does not
 // return.
 func Execute(version, date string) {
-	if err := Command(version, date).Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "aws-auth: %v\n", err)
-		os.Exit(1)
+	if err := Command(version, date).ExecuteContext(interruptContext()); err != nil {
+		var failed failures
+		if !errors.As(err, &failed) {
+			failed = failures{{Err: err}}
+		}
+
+		for _, result := range failed {
+			prefix := "aws-auth: "
+			if result.Profile != "" {
+				prefix += "profile " + result.Profile + ": "
+			}
+
+			fmt.Fprintf(os.Stderr, "%s%v\n", prefix, result.Err)
+			if hint := transformers.Hint(result.Err); hint != "" {
+				fmt.Fprintf(os.Stderr, "%shint: %s\n", prefix, hint)
+			}
+		}
+		os.Exit(transformers.ExitCode(err))
 	}
 	os.Exit(0)
 }
 
+// failures is the results for every profile that credentials could not be
+// obtained for. The first failure determines the exit code.
+type failures []transformers.Result
+
+func (f failures) Error() string {
+	messages := make([]string, len(f))
+	for index, result := range f {
+		messages[index] = fmt.Sprintf("profile %s: %v", result.Profile, result.Err)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (f failures) Unwrap() error {
+	return f[0].Err
+}
+
+// interruptContext returns a context.Context that is canceled when the process
+// is interrupted, so that any API calls or prompts are cleanly abandoned. A
+// second interrupt terminates the process as usual.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		cancel()
+	}()
+
+	return ctx
+}
+
 // versionTemplate formats the command output when using the --version flag.
 func versionTemplate(version, date string) string {
 	return fmt.Sprintf(
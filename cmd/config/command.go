@@ -0,0 +1,26 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package config
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Command defines the aws-auth config command.
+//
+// $ aws-auth config
+func Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect AWS config files",
+		Long:  "aws-auth config - Inspect AWS config files",
+	}
+
+	cmd.AddCommand(
+		validateCommand(),
+	)
+
+	return cmd
+}
@@ -0,0 +1,46 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/joshdk/aws-auth/config"
+	"github.com/spf13/cobra"
+)
+
+// validateCommand defines the aws-auth config validate command.
+//
+// $ aws-auth config validate
+func validateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check all profiles for configuration problems",
+		Long:  "aws-auth config validate - Check all profiles for configuration problems",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load and check the AWS config files, without making any API
+			// calls.
+			problems, err := config.Validate()
+			if err != nil {
+				return err
+			}
+
+			// Print each problem found.
+			for _, problem := range problems {
+				fmt.Println(problem)
+			}
+
+			// Exit non-zero if there were any problems, for use in CI.
+			if len(problems) > 0 {
+				return fmt.Errorf("found %d configuration problem(s)", len(problems))
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -7,6 +7,7 @@ package console
 import (
 	"fmt"
 
+	"github.com/joshdk/aws-auth/cmd/flags"
 	"github.com/joshdk/aws-auth/config"
 	"github.com/joshdk/aws-auth/console"
 	"github.com/joshdk/aws-auth/transformers"
@@ -26,7 +27,24 @@ func Command() *cobra.Command {
 
 		RunE: func(cmd *cobra.Command, args []string) error {
 			flagBrowser, _ := cmd.Flags().GetBool("browser")
-			flagProfile, _ := cmd.Flags().GetString("profile")
+			flagProfile, err := flags.Profile(cmd)
+			if err != nil {
+				return err
+			}
+			flagSourceProfile, _ := cmd.Flags().GetString("source-profile")
+
+			// Abandon any API calls or prompts after the --timeout elapses.
+			ctx, cancel, err := flags.Context(cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			// The --source-profile flag takes the place of --profile, as the
+			// start of an ad-hoc chain.
+			if flagSourceProfile != "" {
+				flagProfile = flagSourceProfile
+			}
 
 			// Load and parse the AWS config files.
 			cfg, err := config.Load()
@@ -35,19 +53,19 @@ func Command() *cobra.Command {
 			}
 
 			// Find a chain of transforms for obtaining profile credentials.
-			startCreds, transforms, err := transformers.Chain(cfg, flagProfile)
+			target, err := flags.Target(cmd, cfg, flagProfile)
 			if err != nil {
 				return err
 			}
 
 			// Make all of the transforms needed to obtain those credentials.
-			endCreds, err := transformers.Transform(startCreds, transforms)
+			endCreds, err := transformers.Transform(ctx, target.Creds, target.Transforms)
 			if err != nil {
 				return err
 			}
 
 			// Generate an AWS Console login URL.
-			url, err := console.GenerateLoginURL(endCreds)
+			url, err := console.GenerateLoginURL(ctx, endCreds, target.Endpoint)
 			if err != nil {
 				return err
 			}
@@ -66,6 +84,8 @@ func Command() *cobra.Command {
 	}
 
 	cmd.Flags().BoolP("browser", "b", false, "open url with default browser")
+	flags.AddSourceProfile(cmd)
+	flags.AddChain(cmd)
 
 	return cmd
 }
@@ -0,0 +1,229 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package each
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joshdk/aws-auth/cmd/flags"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/transformers"
+	"github.com/spf13/cobra"
+)
+
+// outcome describes the result of running the command for a single profile.
+type outcome struct {
+	Profile  string
+	Duration time.Duration
+	Err      error
+}
+
+// Command defines the aws-auth each command.
+//
+// $ aws-auth each --match 'prod-*' -- aws s3 ls
+func Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "each --match PATTERN [--parallel N] -- COMMAND [ARGS...]",
+		Short: "Run a command with credentials for many profiles",
+		Long:  "aws-auth each - Run a command with credentials for many profiles",
+		Args:  cobra.MinimumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flagMatch, _ := cmd.Flags().GetStringArray("match")
+			flagParallel, _ := cmd.Flags().GetInt("parallel")
+
+			if flagParallel < 1 {
+				return fmt.Errorf("--parallel must be at least 1")
+			}
+
+			// Abandon any API calls or prompts after the --timeout elapses.
+			// The commands themselves are not bound by the --timeout, and
+			// are only stopped if aws-auth is interrupted.
+			ctx, cancel, err := flags.Context(cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			// Load and parse the AWS config files.
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			// Find every profile matching any of the given patterns.
+			profiles, err := cfg.Match(flagMatch...)
+			if err != nil {
+				return err
+			}
+			if len(profiles) == 0 {
+				return fmt.Errorf("no profiles match %s", strings.Join(flagMatch, ", "))
+			}
+
+			// Find a chain of transforms for obtaining credentials for each
+			// profile. Misconfigured profiles are reported as failures,
+			// rather than stopping the others.
+			outcomes := make([]outcome, len(profiles))
+			var targets []transformers.Target
+			var indices []int
+			for index, profile := range profiles {
+				outcomes[index].Profile = profile
+
+				target, err := flags.Target(cmd, cfg, profile)
+				if err != nil {
+					outcomes[index].Err = err
+					continue
+				}
+				targets = append(targets, target)
+				indices = append(indices, index)
+			}
+
+			// Obtain credentials for every profile. Profiles that share a
+			// source session share its MFA prompt too.
+			results := transformers.Resolve(ctx, targets)
+
+			// Run the command for each profile, at most --parallel at once.
+			width := longest(profiles)
+			lock := &sync.Mutex{}
+			limit := make(chan struct{}, flagParallel)
+
+			var wg sync.WaitGroup
+			for position, result := range results {
+				index := indices[position]
+				if result.Err != nil {
+					outcomes[index].Err = result.Err
+					continue
+				}
+
+				wg.Add(1)
+				go func(index int, result transformers.Result) {
+					defer wg.Done()
+					limit <- struct{}{}
+					defer func() { <-limit }()
+
+					prefix := fmt.Sprintf("%-*s | ", width, result.Profile)
+					outcomes[index].Duration, outcomes[index].Err = run(cmd.Context(), args, result.Identity, prefix, lock)
+				}(index, result)
+			}
+			wg.Wait()
+
+			return summarize(os.Stderr, outcomes)
+		},
+	}
+
+	cmd.Flags().StringArrayP("match", "m", []string{"*"}, "glob pattern of profiles to run the command for, may be repeated")
+	cmd.Flags().IntP("parallel", "P", 4, "number of commands to run at once")
+	flags.AddChain(cmd)
+
+	return cmd
+}
+
+// run runs the given command with the environment of the given identity.
+// Each line of output is prefixed with the given prefix, and written while
+// holding the given lock so that lines from concurrent commands don't mix.
+func run(ctx context.Context, args []string, identity *transformers.Identity, prefix string, lock *sync.Mutex) (time.Duration, error) {
+	stdout := &prefixWriter{out: os.Stdout, prefix: prefix, lock: lock}
+	stderr := &prefixWriter{out: os.Stderr, prefix: prefix, lock: lock}
+	defer stdout.Flush()
+	defer stderr.Flush()
+
+	command := exec.CommandContext(ctx, args[0], args[1:]...)
+	command.Env = environ(os.Environ(), identity)
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	start := time.Now()
+	err := command.Run()
+	return time.Since(start), err
+}
+
+// environ returns the given environment, with any AWS credentials or profile
+// selection replaced by those of the given identity.
+func environ(base []string, identity *transformers.Identity) []string {
+	replaced := map[string]bool{
+		"AWS_ACCESS_KEY_ID":     true,
+		"AWS_DEFAULT_PROFILE":   true,
+		"AWS_PROFILE":           true,
+		"AWS_SECRET_ACCESS_KEY": true,
+		"AWS_SECURITY_TOKEN":    true,
+		"AWS_SESSION_TOKEN":     true,
+	}
+
+	vars := identity.Env()
+
+	var env []string
+	for _, pair := range base {
+		key := strings.SplitN(pair, "=", 2)[0]
+		if _, found := vars[key]; found || replaced[key] {
+			continue
+		}
+		env = append(env, pair)
+	}
+
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		env = append(env, key+"="+vars[key])
+	}
+
+	return env
+}
+
+// summarize prints a table to the given writer describing whether the command
+// succeeded for each profile, and returns an error if any failed.
+func summarize(out io.Writer, outcomes []outcome) error {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PROFILE\tSTATUS\tDURATION\tERROR")
+
+	var failures int
+	for _, outcome := range outcomes {
+		status, message := "ok", "-"
+		if outcome.Err != nil {
+			status, message = "failed", strings.ReplaceAll(outcome.Err.Error(), "\n", " ")
+			failures++
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n",
+			outcome.Profile,
+			status,
+			outcome.Duration.Round(time.Millisecond),
+			message,
+		)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("command failed for %d of %d profile(s)", failures, len(outcomes))
+	}
+
+	return nil
+}
+
+// longest returns the length of the longest of the given names.
+func longest(names []string) int {
+	var width int
+	for _, name := range names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	return width
+}
@@ -0,0 +1,135 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package each
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joshdk/aws-auth/transformers"
+)
+
+func TestEnviron(t *testing.T) {
+	tests := []struct {
+		base     []string
+		identity transformers.Identity
+		expected []string
+	}{
+		{
+			base: []string{
+				"HOME=/home/alice",
+				"PATH=/usr/bin",
+			},
+			identity: transformers.Identity{
+				AccessKeyID:     "AKIAALICE",
+				AccountID:       "000000000000",
+				ARN:             "arn:aws:iam::000000000000:user/alice",
+				SecretAccessKey: "secret",
+			},
+			expected: []string{
+				"HOME=/home/alice",
+				"PATH=/usr/bin",
+				"AWS_ACCESS_KEY_ID=AKIAALICE",
+				"AWS_ACCOUNT_ID=000000000000",
+				"AWS_ARN=arn:aws:iam::000000000000:user/alice",
+				"AWS_SECRET_ACCESS_KEY=secret",
+			},
+		},
+		{
+			base: []string{
+				"AWS_ACCESS_KEY_ID=AKIAOLD",
+				"AWS_SECRET_ACCESS_KEY=old",
+				"AWS_SESSION_TOKEN=old",
+				"AWS_SECURITY_TOKEN=old",
+				"AWS_PROFILE=old",
+				"AWS_DEFAULT_PROFILE=old",
+				"AWS_REGION=us-west-2",
+				"AWS_PAGER=",
+				"HOME=/home/alice",
+			},
+			identity: transformers.Identity{
+				AccessKeyID:     "ASIAADMIN",
+				AccountID:       "111111111111",
+				ARN:             "arn:aws:sts::111111111111:assumed-role/admin/Temp",
+				Expiration:      time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				Region:          "us-east-1",
+				SecretAccessKey: "secret",
+				SessionToken:    "token",
+			},
+			expected: []string{
+				"AWS_PAGER=",
+				"HOME=/home/alice",
+				"AWS_ACCESS_KEY_ID=ASIAADMIN",
+				"AWS_ACCOUNT_ID=111111111111",
+				"AWS_ARN=arn:aws:sts::111111111111:assumed-role/admin/Temp",
+				"AWS_DEFAULT_REGION=us-east-1",
+				"AWS_EXPIRATION=2020-01-01 00:00:00 +0000 UTC",
+				"AWS_REGION=us-east-1",
+				"AWS_SECRET_ACCESS_KEY=secret",
+				"AWS_SESSION_TOKEN=token",
+			},
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			actual := environ(test.base, &test.identity)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("expected environment %q but got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		outcomes []outcome
+		expected string
+		err      bool
+	}{
+		{
+			outcomes: []outcome{
+				{Profile: "prod-admin", Duration: 1200 * time.Millisecond},
+				{Profile: "prod-readonly", Duration: 1100 * time.Millisecond},
+			},
+			expected: "" +
+				"PROFILE        STATUS  DURATION  ERROR\n" +
+				"prod-admin     ok      1.2s      -\n" +
+				"prod-readonly  ok      1.1s      -\n",
+		},
+		{
+			outcomes: []outcome{
+				{Profile: "prod-admin", Duration: 1200 * time.Millisecond},
+				{Profile: "prod-broken", Err: errors.New("exit status 1\nmore")},
+			},
+			expected: "" +
+				"PROFILE      STATUS  DURATION  ERROR\n" +
+				"prod-admin   ok      1.2s      -\n" +
+				"prod-broken  failed  0s        exit status 1 more\n",
+			err: true,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			var buf bytes.Buffer
+			err := summarize(&buf, test.outcomes)
+			switch {
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if actual := buf.String(); actual != test.expected {
+				t.Fatalf("expected output:\n%s\nbut got:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package each
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// prefixWriter writes each line written to it to another writer, preceded by
+// a prefix. Only whole lines are written, while holding a lock shared with
+// other writers, so that lines written concurrently don't mix.
+type prefixWriter struct {
+	out    io.Writer
+	prefix string
+	lock   *sync.Mutex
+	buffer []byte
+}
+
+func (w *prefixWriter) Write(data []byte) (int, error) {
+	w.buffer = append(w.buffer, data...)
+
+	// Write out every complete line, and keep any partial line for later.
+	if end := bytes.LastIndexByte(w.buffer, '\n'); end >= 0 {
+		lines := w.buffer[:end+1]
+		if err := w.write(lines); err != nil {
+			return 0, err
+		}
+		w.buffer = append([]byte{}, w.buffer[end+1:]...)
+	}
+
+	return len(data), nil
+}
+
+// Flush writes out any remaining partial line.
+func (w *prefixWriter) Flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	err := w.write(append(w.buffer, '\n'))
+	w.buffer = nil
+	return err
+}
+
+// write writes the given newline-terminated lines, each with the prefix.
+func (w *prefixWriter) write(lines []byte) error {
+	var prefixed bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) > 0 {
+			prefixed.WriteString(w.prefix)
+			prefixed.Write(line)
+		}
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	_, err := w.out.Write(prefixed.Bytes())
+	return err
+}
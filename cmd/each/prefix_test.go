@@ -0,0 +1,128 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package each
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		writes   []string
+		flushed  string
+		expected string
+	}{
+		{},
+		{
+			writes:   []string{"hello\n"},
+			flushed:  "dev | hello\n",
+			expected: "dev | hello\n",
+		},
+		{
+			writes:   []string{"hel", "lo\nwor", "ld\n"},
+			flushed:  "dev | hello\ndev | world\n",
+			expected: "dev | hello\ndev | world\n",
+		},
+		{
+			writes:   []string{"one\n\ntwo\n"},
+			flushed:  "dev | one\ndev | \ndev | two\n",
+			expected: "dev | one\ndev | \ndev | two\n",
+		},
+		{
+			writes:   []string{"partial"},
+			expected: "dev | partial\n",
+		},
+		{
+			writes:   []string{"line\npart", "ial"},
+			flushed:  "dev | line\n",
+			expected: "dev | line\ndev | partial\n",
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := &prefixWriter{out: &buf, prefix: "dev | ", lock: &sync.Mutex{}}
+
+			for _, write := range test.writes {
+				n, err := writer.Write([]byte(write))
+				if err != nil {
+					t.Fatalf("expected no error but got error %q", err)
+				}
+				if n != len(write) {
+					t.Fatalf("expected %d bytes written but got %d", len(write), n)
+				}
+			}
+
+			// Partial lines are held back until flushed.
+			if actual := buf.String(); actual != test.flushed {
+				t.Fatalf("expected output %q before flush but got %q", test.flushed, actual)
+			}
+
+			if err := writer.Flush(); err != nil {
+				t.Fatalf("expected no error but got error %q", err)
+			}
+
+			if actual := buf.String(); actual != test.expected {
+				t.Fatalf("expected output %q but got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPrefixWriterConcurrent(t *testing.T) {
+	const (
+		writers = 8
+		lines   = 100
+	)
+
+	var buf bytes.Buffer
+	lock := &sync.Mutex{}
+
+	// Each writer writes its lines a few bytes at a time, so that lines from
+	// different writers would interleave if they weren't written whole.
+	var wg sync.WaitGroup
+	for index := 0; index < writers; index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			writer := &prefixWriter{out: &buf, prefix: fmt.Sprintf("p%d | ", index), lock: lock}
+			for line := 0; line < lines; line++ {
+				text := fmt.Sprintf("line %d from writer %d\n", line, index)
+				for len(text) > 0 {
+					size := 3
+					if size > len(text) {
+						size = len(text)
+					}
+					writer.Write([]byte(text[:size]))
+					text = text[size:]
+				}
+			}
+			writer.Flush()
+		}(index)
+	}
+	wg.Wait()
+
+	var expected []string
+	for index := 0; index < writers; index++ {
+		for line := 0; line < lines; line++ {
+			expected = append(expected, fmt.Sprintf("p%d | line %d from writer %d", index, line, index))
+		}
+	}
+	sort.Strings(expected)
+
+	actual := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	sort.Strings(actual)
+
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected %d whole prefixed lines but got:\n%s", len(expected), buf.String())
+	}
+}
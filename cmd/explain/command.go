@@ -0,0 +1,75 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package explain
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/joshdk/aws-auth/cmd/flags"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/transformers"
+	"github.com/spf13/cobra"
+)
+
+// Command defines the aws-auth explain command.
+//
+// $ aws-auth explain
+func Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain",
+		Short: "Describe the steps taken to obtain credentials for a profile",
+		Long:  "aws-auth explain - Describe the steps taken to obtain credentials for a profile",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flagProfile, err := flags.Profile(cmd)
+			if err != nil {
+				return err
+			}
+
+			// Load and parse the AWS config files.
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			// Find a chain of transforms for obtaining profile credentials.
+			startCreds, transforms, err := transformers.Chain(cfg, flagProfile)
+			if err != nil {
+				return err
+			}
+
+			// Describe all of the transforms, without performing them.
+			steps := transformers.Explain(startCreds, transforms)
+			if len(steps) == 0 {
+				fmt.Printf("Profile %s has credentials that are used directly, no API calls are needed.\n", flagProfile)
+				return nil
+			}
+
+			fmt.Printf("Profile %s obtains credentials in %d step(s):\n", flagProfile, len(steps))
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for index, step := range steps {
+				fmt.Fprintf(writer, "\n%d. %s for profile %s\n", index+1, step.API, step.Profile)
+				if step.Source != "" {
+					fmt.Fprintf(writer, "   using credentials from profile %s\n", step.Source)
+				}
+
+				for _, detail := range step.Details {
+					fmt.Fprintf(writer, "   %s:\t%s\n", detail.Label, detail.Value)
+				}
+
+				for _, warning := range step.Warnings {
+					fmt.Fprintf(writer, "   warning:\t%s\n", warning)
+				}
+			}
+
+			return writer.Flush()
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,67 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package fakests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/joshdk/aws-auth/fakests"
+	"github.com/spf13/cobra"
+)
+
+// Command defines the aws-auth fake-sts command.
+//
+// $ aws-auth fake-sts --config users.json
+func Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fake-sts",
+		Short: "Run a local fake STS server for testing profiles",
+		Long:  "aws-auth fake-sts - Run a local fake STS server for testing profiles",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flagConfig, _ := cmd.Flags().GetString("config")
+			flagListen, _ := cmd.Flags().GetString("listen")
+
+			// Load the users and roles that the server knows about.
+			cfg, err := fakests.LoadConfig(flagConfig)
+			if err != nil {
+				return err
+			}
+
+			listener, err := net.Listen("tcp", flagListen)
+			if err != nil {
+				return err
+			}
+
+			server := &http.Server{
+				Handler: fakests.New(cfg),
+			}
+
+			// Serve until interrupted.
+			ctx := cmd.Context()
+			go func() {
+				<-ctx.Done()
+				server.Shutdown(context.Background())
+			}()
+
+			fmt.Fprintf(os.Stderr, "Serving fake STS on http://%s\n", listener.Addr())
+			if err := server.Serve(listener); err != http.ErrServerClosed {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("config", "c", "", "JSON file of users and roles")
+	cmd.Flags().StringP("listen", "l", "127.0.0.1:4566", "address to listen on")
+	cmd.MarkFlagRequired("config")
+
+	return cmd
+}
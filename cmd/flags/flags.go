@@ -0,0 +1,219 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+// Package flags reads the global flags that are shared by every command.
+package flags
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/httpclient"
+	"github.com/joshdk/aws-auth/transformers"
+	"github.com/spf13/cobra"
+)
+
+// Context returns the command's context.Context, bounded by the duration
+// given with the global --timeout flag. A zero duration means no timeout.
+// Transforms are traced to stderr if the global --verbose flag is given, and
+// to a JSON file if the global --trace flag is given. HTTP requests and
+// responses are dumped to stderr if the global --debug-http flag is given,
+// recorded to a file if the global --record flag is given, and served from a
+// file rather than made if the global --replay flag is given. The returned
+// context.CancelFunc must be called once the command is done.
+func Context(cmd *cobra.Command) (context.Context, context.CancelFunc, error) {
+	flagTimeout, _ := cmd.Flags().GetDuration("timeout")
+	flagVerbose, _ := cmd.Flags().GetBool("verbose")
+	flagTrace, _ := cmd.Flags().GetString("trace")
+	flagDebugHTTP, _ := cmd.Flags().GetBool("debug-http")
+	flagRecord, _ := cmd.Flags().GetString("record")
+	flagReplay, _ := cmd.Flags().GetString("replay")
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if flagTimeout > 0 {
+		ctx, cancel = context.WithTimeout(cmd.Context(), flagTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(cmd.Context())
+	}
+
+	// Notices, like a session duration being shortened, are always shown.
+	ctx = transformers.WithNotices(ctx, os.Stderr)
+
+	var tracers []transformers.Tracer
+	if flagVerbose {
+		tracers = append(tracers, textTracer(os.Stderr))
+	}
+
+	if flagTrace != "" {
+		file, err := os.Create(flagTrace)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+
+		tracers = append(tracers, jsonTracer(file, os.Stderr))
+
+		// Close the trace file once the command is done.
+		cancelContext := cancel
+		cancel = func() {
+			cancelContext()
+			file.Close()
+		}
+	}
+
+	if len(tracers) > 0 {
+		ctx = transformers.WithTracer(ctx, combine(tracers))
+	}
+
+	// Every request in the run is made with a single client, so that
+	// connections are reused.
+	client := httpclient.New()
+
+	// Serve recorded responses rather than making any actual requests.
+	if flagReplay != "" {
+		file, err := os.Open(flagReplay)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		defer file.Close()
+
+		replay, err := httpclient.Replay(file)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+
+		client.Wrap(func(http.RoundTripper) http.RoundTripper {
+			return replay
+		})
+	}
+
+	// Record every HTTP request and response to a file, with any secrets
+	// redacted.
+	if flagRecord != "" {
+		file, err := os.Create(flagRecord)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+
+		client.Wrap(func(next http.RoundTripper) http.RoundTripper {
+			return httpclient.Record(next, file)
+		})
+
+		// Close the record file once the command is done.
+		cancelContext := cancel
+		cancel = func() {
+			cancelContext()
+			file.Close()
+		}
+	}
+
+	// Dump every HTTP request and response to stderr, with any secrets
+	// redacted.
+	if flagDebugHTTP {
+		client.Wrap(func(next http.RoundTripper) http.RoundTripper {
+			return httpclient.Debug(next, os.Stderr)
+		})
+	}
+
+	ctx = httpclient.WithClient(ctx, client)
+
+	return ctx, cancel, nil
+}
+
+// Profiles returns every profile given with the global --profile flag.
+func Profiles(cmd *cobra.Command) []string {
+	flagProfiles, _ := cmd.Flags().GetStringArray("profile")
+	return flagProfiles
+}
+
+// Profile returns the profile given with the global --profile flag, for
+// commands that only target a single profile.
+func Profile(cmd *cobra.Command) (string, error) {
+	flagProfiles := Profiles(cmd)
+	if len(flagProfiles) != 1 {
+		return "", fmt.Errorf("exactly one --profile must be given, but %d were", len(flagProfiles))
+	}
+
+	return flagProfiles[0], nil
+}
+
+// AddSourceProfile adds the --source-profile flag, for starting an ad-hoc
+// chain from a profile in place of --profile, to the given command.
+func AddSourceProfile(cmd *cobra.Command) {
+	cmd.Flags().String("source-profile", "", "config profile to start an ad-hoc chain from")
+}
+
+// AddChain adds the flags read by Target, for adding ad-hoc transforms and
+// session tags to the end of a profile's chain, to the given command. Only
+// commands that obtain credentials have these flags, so that they are never
+// silently ignored.
+func AddChain(cmd *cobra.Command) {
+	cmd.Flags().String("role-arn", "", "role to assume at the end of an ad-hoc chain")
+	cmd.Flags().String("external-id", "", "external id for assuming --role-arn")
+	cmd.Flags().String("mfa-serial", "", "MFA device for assuming --role-arn")
+	cmd.Flags().StringArray("via", nil, "ad-hoc hop (role:ARN or session[:MFA_SERIAL]), may be repeated")
+	cmd.Flags().StringArray("tag", nil, "session tag (key=value) for the final role or federation token, may be repeated")
+}
+
+// Target finds a chain of transforms for obtaining credentials for the named
+// profile, followed by any ad-hoc transforms and session tags given with the
+// --via, --role-arn, --external-id, --mfa-serial, and --tag flags added by
+// AddChain.
+func Target(cmd *cobra.Command, cfg *config.Config, profile string) (transformers.Target, error) {
+	flagRoleARN, _ := cmd.Flags().GetString("role-arn")
+	flagExternalID, _ := cmd.Flags().GetString("external-id")
+	flagMFASerial, _ := cmd.Flags().GetString("mfa-serial")
+	flagVia, _ := cmd.Flags().GetStringArray("via")
+	flagTags, _ := cmd.Flags().GetStringArray("tag")
+
+	// Find a chain of transforms for obtaining profile credentials.
+	startCreds, transforms, err := transformers.Chain(cfg, profile)
+	if err != nil {
+		return transformers.Target{}, err
+	}
+
+	// Determine which endpoint the profile uses for API calls.
+	endpoint, err := cfg.Endpoint(profile)
+	if err != nil {
+		return transformers.Target{}, err
+	}
+
+	// Add any ad-hoc transforms given on the command line to the end of the
+	// chain.
+	adHoc, err := transformers.AdHoc(profile, endpoint, transformers.AdHocOptions{
+		Via:        flagVia,
+		RoleARN:    flagRoleARN,
+		ExternalID: flagExternalID,
+		MFASerial:  flagMFASerial,
+	})
+	if err != nil {
+		return transformers.Target{}, err
+	}
+	transforms = append(transforms, adHoc...)
+
+	// Add any session tags given on the command line to the final transform
+	// in the chain.
+	tags, err := config.ParseTags(flagTags...)
+	if err != nil {
+		return transformers.Target{}, err
+	}
+	transforms, err = transformers.WithTags(transforms, tags)
+	if err != nil {
+		return transformers.Target{}, err
+	}
+
+	return transformers.Target{
+		Profile:    profile,
+		Creds:      startCreds,
+		Transforms: transforms,
+		Endpoint:   endpoint,
+	}, nil
+}
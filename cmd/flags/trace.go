@@ -0,0 +1,100 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/joshdk/aws-auth/transformers"
+)
+
+// traceEntry is a single line of a JSON trace file.
+type traceEntry struct {
+	Profile    string  `json:"profile"`
+	API        string  `json:"api"`
+	Target     string  `json:"target,omitempty"`
+	Start      string  `json:"start"`
+	Seconds    float64 `json:"seconds"`
+	ARN        string  `json:"arn,omitempty"`
+	Expiration string  `json:"expiration,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// textTracer returns a Tracer that writes a line describing each event to
+// the given writer.
+func textTracer(out io.Writer) transformers.Tracer {
+	var lock sync.Mutex
+
+	return func(event transformers.Event) {
+		line := fmt.Sprintf("aws-auth: trace: %s %s", event.Profile, event.API)
+		if event.Target != "" {
+			line += " " + event.Target
+		}
+		line += fmt.Sprintf(" (%s)", event.Duration.Round(time.Millisecond))
+
+		switch {
+		case event.Error != "":
+			line += " failed: " + event.Error
+		case event.ARN != "" && !event.Expiration.IsZero():
+			line += fmt.Sprintf(" → %s, expires %s", event.ARN, event.Expiration.Format(time.RFC3339))
+		case event.ARN != "":
+			line += " → " + event.ARN
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		fmt.Fprintln(out, line)
+	}
+}
+
+// jsonTracer returns a Tracer that writes a JSON object describing each
+// event to the given writer, one per line. If writing an event fails, the
+// failure is reported to the given warnings writer, and no further events
+// are written.
+func jsonTracer(out, warnings io.Writer) transformers.Tracer {
+	var lock sync.Mutex
+	var failed bool
+	encoder := json.NewEncoder(out)
+
+	return func(event transformers.Event) {
+		entry := traceEntry{
+			Profile: event.Profile,
+			API:     event.API,
+			Target:  event.Target,
+			Start:   event.Start.Format(time.RFC3339Nano),
+			Seconds: event.Duration.Seconds(),
+			ARN:     event.ARN,
+			Error:   event.Error,
+		}
+
+		if !event.Expiration.IsZero() {
+			entry.Expiration = event.Expiration.Format(time.RFC3339)
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		if failed {
+			return
+		}
+		if err := encoder.Encode(entry); err != nil {
+			failed = true
+			fmt.Fprintf(warnings, "aws-auth: writing trace failed: %v\n", err)
+		}
+	}
+}
+
+// combine returns a Tracer that calls each of the given tracers.
+func combine(tracers []transformers.Tracer) transformers.Tracer {
+	return func(event transformers.Event) {
+		for _, tracer := range tracers {
+			tracer(event)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package flags
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/fakests"
+	"github.com/joshdk/aws-auth/transformers"
+)
+
+func TestTracers(t *testing.T) {
+	server := httptest.NewServer(fakests.New(fakests.Config{
+		Users: []fakests.User{
+			{
+				ARN:             "arn:aws:iam::111111111111:user/alice",
+				AccessKeyID:     "AKIAALICE",
+				SecretAccessKey: "alice-secret",
+			},
+		},
+		Roles: []fakests.Role{
+			{
+				ARN:   "arn:aws:iam::222222222222:role/admin",
+				Trust: []string{"arn:aws:iam::111111111111:user/alice"},
+			},
+		},
+	}))
+	defer server.Close()
+
+	endpoint := config.Endpoint{
+		EndpointURL: server.URL,
+		Region:      "us-east-1",
+	}
+
+	tests := []struct {
+		roleARN string
+		arn     string
+		err     bool
+	}{
+		{
+			roleARN: "arn:aws:iam::222222222222:role/admin",
+			arn:     "arn:aws:sts::222222222222:assumed-role/admin/Temp",
+		},
+		{
+			roleARN: "arn:aws:iam::333333333333:role/missing",
+			err:     true,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			var events []transformers.Event
+			var text, json, warnings bytes.Buffer
+			ctx := transformers.WithTracer(context.Background(), combine([]transformers.Tracer{
+				func(event transformers.Event) {
+					events = append(events, event)
+				},
+				textTracer(&text),
+				jsonTracer(&json, &warnings),
+			}))
+
+			creds, err := transformers.Transform(ctx, &sts.Credentials{
+				AccessKeyId:     aws.String("AKIAALICE"),
+				SecretAccessKey: aws.String("alice-secret"),
+			}, []transformers.Transformer{
+				transformers.AssumeRoleTransform{Profile: "admin", Role: &config.Role{
+					Endpoint: endpoint,
+					RoleARN:  test.roleARN,
+				}},
+			})
+			switch {
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if len(events) != 1 {
+				t.Fatalf("expected 1 event but got %d", len(events))
+			}
+			event := events[0]
+
+			if event.ARN != test.arn {
+				t.Fatalf("expected arn %q but got %q", test.arn, event.ARN)
+			}
+
+			// Only successful steps obtain credentials that expire.
+			secrets := []string{"alice-secret"}
+			if test.err {
+				if event.Error == "" || !event.Expiration.IsZero() {
+					t.Fatalf("expected a failed event but got %+v", event)
+				}
+			} else {
+				if !event.Expiration.Equal(aws.TimeValue(creds.Expiration)) {
+					t.Fatalf("expected expiration %s but got %s", aws.TimeValue(creds.Expiration), event.Expiration)
+				}
+				secrets = append(secrets, aws.StringValue(creds.SecretAccessKey), aws.StringValue(creds.SessionToken))
+			}
+
+			// Secrets must never appear in any trace output.
+			for name, output := range map[string]string{"text": text.String(), "json": json.String()} {
+				if !strings.Contains(output, "sts:AssumeRole") || (test.arn != "" && !strings.Contains(output, test.arn)) {
+					t.Fatalf("expected %s trace of the step but got %q", name, output)
+				}
+				for _, secret := range secrets {
+					if strings.Contains(output, secret) {
+						t.Fatalf("expected %s trace without secrets but got %q", name, output)
+					}
+				}
+			}
+
+			if warnings.Len() != 0 {
+				t.Fatalf("expected no warnings but got %q", warnings.String())
+			}
+		})
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestJSONTracerFailure(t *testing.T) {
+	var warnings bytes.Buffer
+	tracer := jsonTracer(failingWriter{}, &warnings)
+
+	// Only the first failure is reported.
+	tracer(transformers.Event{Profile: "admin"})
+	tracer(transformers.Event{Profile: "admin"})
+
+	expected := "aws-auth: writing trace failed: disk full\n"
+	if warnings.String() != expected {
+		t.Fatalf("expected warnings %q but got %q", expected, warnings.String())
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package graph
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joshdk/aws-auth/cmd/flags"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/spf13/cobra"
+)
+
+// colors maps each profile type to the color used to fill its node.
+var colors = map[string]string{
+	config.TypeUser:     "#a6cee3",
+	config.TypeRole:     "#b2df8a",
+	config.TypeSession:  "#fdbf6f",
+	config.TypeFederate: "#cab2d6",
+	config.TypeSAML:     "#fb9a99",
+	"":                  "#d9d9d9",
+}
+
+// operations maps each profile type to the API call made to obtain
+// credentials from its source profile.
+var operations = map[string]string{
+	config.TypeRole:     "assume-role",
+	config.TypeSession:  "get-session-token",
+	config.TypeFederate: "get-federation-token",
+}
+
+// Command defines the aws-auth graph command.
+//
+// $ aws-auth graph
+func Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Render the graph of profile chains",
+		Long:  "aws-auth graph - Render the graph of profile chains",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flagFormat, _ := cmd.Flags().GetString("format")
+			flagProfiles := flags.Profiles(cmd)
+
+			var render func(io.Writer, []config.Summary) error
+			switch flagFormat {
+			case "dot":
+				render = renderDOT
+			case "mermaid":
+				render = renderMermaid
+			default:
+				return fmt.Errorf("unknown graph format %q", flagFormat)
+			}
+
+			// Load and parse the AWS config files.
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			// Graph every profile, unless specific profiles were targeted.
+			names := cfg.Profiles()
+			if cmd.Flags().Changed("profile") {
+				names = flagProfiles
+			}
+
+			return render(os.Stdout, collect(cfg, names))
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "dot", "graph format (dot or mermaid)")
+
+	return cmd
+}
+
+// collect summarizes the named profiles, along with every profile that they
+// (transitively) reference as a source profile. Summaries are returned in
+// sorted order.
+func collect(cfg *config.Config, names []string) []config.Summary {
+	summaries := make(map[string]config.Summary)
+
+	var visit func(name string)
+	visit = func(name string) {
+		if _, found := summaries[name]; found {
+			return
+		}
+
+		summary := cfg.Summarize(name)
+		summaries[name] = summary
+
+		if summary.SourceProfile != "" {
+			visit(summary.SourceProfile)
+		}
+	}
+
+	for _, name := range names {
+		visit(name)
+	}
+
+	result := make([]config.Summary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// label returns the lines of text displayed within the node for the given
+// profile.
+func label(summary config.Summary) []string {
+	switch {
+	case summary.Err != nil:
+		return []string{summary.Name, "(invalid)"}
+	case summary.MFASerial != "":
+		return []string{summary.Name, "(" + summary.Type + ", MFA)"}
+	default:
+		return []string{summary.Name, "(" + summary.Type + ")"}
+	}
+}
+
+// dotEscaper escapes text for use within a quoted DOT string. Only quotes
+// and backslashes have special meaning, newlines are written as line breaks,
+// and any other characters (including non-ASCII ones) are used verbatim.
+var dotEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+)
+
+// dotQuote returns the given lines as a quoted DOT string, separated by line
+// breaks.
+// "prod", "(role)" → "\"prod\n(role)\""
+func dotQuote(lines ...string) string {
+	escaped := make([]string, len(lines))
+	for index, line := range lines {
+		escaped[index] = dotEscaper.Replace(line)
+	}
+	return `"` + strings.Join(escaped, `\n`) + `"`
+}
+
+// renderDOT writes the given profiles as a Graphviz DOT graph.
+// https://graphviz.org/doc/info/lang.html
+func renderDOT(w io.Writer, summaries []config.Summary) error {
+	fmt.Fprintln(w, "digraph profiles {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, `  node [shape=box, style="rounded,filled"];`)
+
+	for _, summary := range summaries {
+		// Nodes for profiles that require MFA are given a thicker border.
+		penwidth := 1
+		if summary.MFASerial != "" {
+			penwidth = 3
+		}
+
+		fmt.Fprintf(w, "  %s [label=%s, fillcolor=%s, penwidth=%d];\n",
+			dotQuote(summary.Name), dotQuote(label(summary)...), dotQuote(colors[summary.Type]), penwidth)
+	}
+
+	for _, summary := range summaries {
+		if summary.SourceProfile != "" {
+			fmt.Fprintf(w, "  %s -> %s [label=%s];\n",
+				dotQuote(summary.SourceProfile), dotQuote(summary.Name), dotQuote(operations[summary.Type]))
+		}
+	}
+
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// mermaidInvalidChars matches characters that are not allowed in a Mermaid
+// node id.
+var mermaidInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// mermaidEscaper escapes text for use within a quoted Mermaid label, using
+// Mermaid's entity codes for characters that would otherwise end the label,
+// start an entity code, or be read as HTML.
+var mermaidEscaper = strings.NewReplacer(
+	"#", "#35;",
+	`"`, "#quot;",
+	"<", "#lt;",
+	">", "#gt;",
+)
+
+// mermaidQuote returns the given lines as a quoted Mermaid label, separated
+// by line breaks.
+// "prod", "(role)" → "\"prod<br/>(role)\""
+func mermaidQuote(lines ...string) string {
+	escaped := make([]string, len(lines))
+	for index, line := range lines {
+		escaped[index] = mermaidEscaper.Replace(line)
+	}
+	return `"` + strings.Join(escaped, "<br/>") + `"`
+}
+
+// renderMermaid writes the given profiles as a Mermaid flowchart.
+// https://mermaid.js.org/syntax/flowchart.html
+func renderMermaid(w io.Writer, summaries []config.Summary) error {
+	// Profile names can contain characters that are not allowed in node ids,
+	// so each node is given a generated id instead.
+	ids := make(map[string]string, len(summaries))
+	for index, summary := range summaries {
+		ids[summary.Name] = fmt.Sprintf("p%d_%s", index, mermaidInvalidChars.ReplaceAllString(summary.Name, "_"))
+	}
+
+	fmt.Fprintln(w, "flowchart LR")
+
+	for _, summary := range summaries {
+		fmt.Fprintf(w, "  %s[%s]\n", ids[summary.Name], mermaidQuote(label(summary)...))
+	}
+
+	for _, summary := range summaries {
+		if summary.SourceProfile != "" {
+			fmt.Fprintf(w, "  %s -- %s --> %s\n",
+				ids[summary.SourceProfile], operations[summary.Type], ids[summary.Name])
+		}
+	}
+
+	// Nodes are styled by type, with a thicker border if MFA is required.
+	for _, summary := range summaries {
+		style := "fill:" + colors[summary.Type]
+		if summary.MFASerial != "" {
+			style += ",stroke-width:3px"
+		}
+		fmt.Fprintf(w, "  style %s %s\n", ids[summary.Name], style)
+	}
+
+	return nil
+}
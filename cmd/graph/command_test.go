@@ -0,0 +1,121 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/joshdk/aws-auth/config"
+)
+
+// summaries is a small graph of profiles, including names with characters
+// that need escaping.
+var summaries = []config.Summary{
+	{
+		Name: "default",
+		Type: config.TypeUser,
+	},
+	{
+		Name: "broken",
+		Err:  errors.New("invalid profile"),
+	},
+	{
+		Name:          `prod "admin"`,
+		Type:          config.TypeRole,
+		SourceProfile: "default",
+		RoleARN:       "arn:aws:iam::000000000000:role/admin",
+		MFASerial:     "arn:aws:iam::000000000000:mfa/alice",
+	},
+	{
+		Name:          `café\#1`,
+		Type:          config.TypeSession,
+		SourceProfile: "default",
+	},
+}
+
+func TestRenderDOT(t *testing.T) {
+	tests := []struct {
+		summaries []config.Summary
+		expected  string
+	}{
+		{
+			expected: `digraph profiles {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled"];
+}
+`,
+		},
+		{
+			summaries: summaries,
+			expected: `digraph profiles {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled"];
+  "default" [label="default\n(user)", fillcolor="#a6cee3", penwidth=1];
+  "broken" [label="broken\n(invalid)", fillcolor="#d9d9d9", penwidth=1];
+  "prod \"admin\"" [label="prod \"admin\"\n(role, MFA)", fillcolor="#b2df8a", penwidth=3];
+  "café\\#1" [label="café\\#1\n(session)", fillcolor="#fdbf6f", penwidth=1];
+  "default" -> "prod \"admin\"" [label="assume-role"];
+  "default" -> "café\\#1" [label="get-session-token"];
+}
+`,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := renderDOT(&buf, test.summaries); err != nil {
+				t.Fatalf("expected no error but got error %q", err)
+			}
+
+			if actual := buf.String(); actual != test.expected {
+				t.Fatalf("expected output:\n%s\nbut got:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRenderMermaid(t *testing.T) {
+	tests := []struct {
+		summaries []config.Summary
+		expected  string
+	}{
+		{
+			expected: `flowchart LR
+`,
+		},
+		{
+			summaries: summaries,
+			expected: `flowchart LR
+  p0_default["default<br/>(user)"]
+  p1_broken["broken<br/>(invalid)"]
+  p2_prod__admin_["prod #quot;admin#quot;<br/>(role, MFA)"]
+  p3_caf___1["café\#35;1<br/>(session)"]
+  p0_default -- assume-role --> p2_prod__admin_
+  p0_default -- get-session-token --> p3_caf___1
+  style p0_default fill:#a6cee3
+  style p1_broken fill:#d9d9d9
+  style p2_prod__admin_ fill:#b2df8a,stroke-width:3px
+  style p3_caf___1 fill:#fdbf6f
+`,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := renderMermaid(&buf, test.summaries); err != nil {
+				t.Fatalf("expected no error but got error %q", err)
+			}
+
+			if actual := buf.String(); actual != test.expected {
+				t.Fatalf("expected output:\n%s\nbut got:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/joshdk/aws-auth/transformers"
+)
+
+// credentials describes the identity obtained for a single profile, or why
+// it could not be obtained.
+type credentials struct {
+	Profile         string `json:"profile"`
+	ARN             string `json:"arn,omitempty"`
+	AccountID       string `json:"account_id,omitempty"`
+	Region          string `json:"region,omitempty"`
+	Expiration      string `json:"expiration,omitempty"`
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+	SessionToken    string `json:"session_token,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// printJSON prints the given results as a JSON list.
+func printJSON(results []transformers.Result) error {
+	entries := make([]credentials, 0, len(results))
+	for _, result := range results {
+		entry := credentials{
+			Profile: result.Profile,
+		}
+
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+			entries = append(entries, entry)
+			continue
+		}
+
+		identity := result.Identity
+		entry.ARN = identity.ARN
+		entry.AccountID = identity.AccountID
+		entry.Region = identity.Region
+		entry.AccessKeyID = identity.AccessKeyID
+		entry.SecretAccessKey = identity.SecretAccessKey
+		entry.SessionToken = identity.SessionToken
+
+		// IAM keys do not have an expiration.
+		if identity.Expiration != (time.Time{}) {
+			entry.Expiration = identity.Expiration.Format(time.RFC3339)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
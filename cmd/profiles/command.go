@@ -0,0 +1,171 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package profiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joshdk/aws-auth/config"
+	"github.com/spf13/cobra"
+)
+
+// entry describes a single profile, along with the chain of profiles used to
+// obtain credentials for it.
+type entry struct {
+	Profile   string   `json:"profile"`
+	Type      string   `json:"type,omitempty"`
+	AccountID string   `json:"account_id,omitempty"`
+	RoleName  string   `json:"role_name,omitempty"`
+	MFA       bool     `json:"mfa"`
+	Chain     []string `json:"chain"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Command defines the aws-auth profiles command.
+//
+// $ aws-auth profiles
+func Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profiles",
+		Short: "List all configured profiles",
+		Long:  "aws-auth profiles - List all configured profiles",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flagAccount, _ := cmd.Flags().GetString("account")
+			flagOutput, _ := cmd.Flags().GetString("output")
+			flagRoleName, _ := cmd.Flags().GetString("role-name")
+
+			if flagOutput != "table" && flagOutput != "json" {
+				return fmt.Errorf("unknown output format %q", flagOutput)
+			}
+
+			// Load and parse the AWS config files.
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			// Describe every profile, keeping only those that match the
+			// given filters.
+			entries := []entry{}
+			for _, name := range cfg.Profiles() {
+				entry := describe(cfg, name)
+				switch {
+				case flagAccount != "" && entry.AccountID != flagAccount:
+					continue
+				case flagRoleName != "" && entry.RoleName != flagRoleName:
+					continue
+				}
+				entries = append(entries, entry)
+			}
+
+			if flagOutput == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(entries)
+			}
+
+			return printTable(entries)
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "table", "output format (table or json)")
+	cmd.Flags().String("account", "", "only list profiles targeting this account id")
+	cmd.Flags().String("role-name", "", "only list profiles targeting this role name")
+
+	return cmd
+}
+
+// describe follows the source profile references for the named profile, and
+// returns an entry describing the resulting chain.
+func describe(cfg *config.Config, name string) entry {
+	target := cfg.Summarize(name)
+	result := entry{
+		Profile:  name,
+		Type:     target.Type,
+		RoleName: target.RoleName(),
+	}
+
+	if target.Err != nil {
+		result.Type = "invalid"
+	}
+
+	// Walk the chain starting from the target profile, whose summary is
+	// reused rather than computed a second time.
+	seen := make(map[string]struct{})
+	for summary := target; ; summary = cfg.Summarize(summary.SourceProfile) {
+		result.Chain = append(result.Chain, summary.Name)
+
+		// Check that we have not visited this profile already, as that would
+		// mean that there is a circular profile reference (mis)configured.
+		if _, found := seen[summary.Name]; found {
+			result.Error = "recursive profile"
+			break
+		}
+		seen[summary.Name] = struct{}{}
+
+		if summary.Err != nil {
+			result.Error = fmt.Sprintf("%s: %v", summary.Name, summary.Err)
+			break
+		}
+
+		// The account is that of the last role assumed, and any profile
+		// deriving credentials from it stays in that account.
+		if result.AccountID == "" {
+			result.AccountID = summary.AccountID()
+		}
+
+		if summary.MFASerial != "" {
+			result.MFA = true
+		}
+
+		if summary.SourceProfile == "" {
+			break
+		}
+	}
+
+	return result
+}
+
+// printTable prints the given entries as an aligned table.
+func printTable(entries []entry) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PROFILE\tTYPE\tACCOUNT\tROLE\tMFA\tCHAIN")
+
+	for _, entry := range entries {
+		mfa := "no"
+		if entry.MFA {
+			mfa = "yes"
+		}
+
+		chain := strings.Join(entry.Chain, " → ")
+		if entry.Error != "" {
+			chain += " (" + entry.Error + ")"
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Profile,
+			orDash(entry.Type),
+			orDash(entry.AccountID),
+			orDash(entry.RoleName),
+			mfa,
+			chain,
+		)
+	}
+
+	return writer.Flush()
+}
+
+// orDash returns the given value, or a dash if it is empty.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -9,8 +9,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
 	"gopkg.in/ini.v1"
@@ -24,29 +26,70 @@ const (
 type Config struct {
 	config      *ini.File
 	credentials *ini.File
+
+	// sources maps each profile name to the files that define it.
+	sources map[string][]string
+
+	// keySources maps each profile name, and each of its keys, to the file
+	// that the key's value was taken from.
+	keySources map[string]map[string]string
+
+	// configFiles and credentialsFiles list every file that was loaded.
+	configFiles      []string
+	credentialsFiles []string
+
+	// failed maps files that failed to load/parse to the error encountered.
+	failed map[string]error
+
+	// includeErrors maps files to the errors encountered expanding their
+	// include directives.
+	includeErrors map[string][]error
+}
+
+// Endpoint describes which region and STS endpoint API calls for a profile
+// are made against.
+type Endpoint struct {
+	CABundle             string
+	EndpointURL          string
+	Region               string
+	SigninURL            string
+	STSRegionalEndpoints string
+	UseDualStackEndpoint bool
+	UseFIPSEndpoint      bool
 }
 
 type User struct {
 	AWSAccessKeyID     string
 	AWSSecretAccessKey string
 	AWSSessionToken    string
+	Endpoint           Endpoint
 }
 
+// MaxDuration is used as the DurationSeconds of a Role or SAML login when
+// configured with "duration_seconds = max", requesting the longest session
+// that the role allows.
+const MaxDuration = -1
+
 type Role struct {
-	DurationSeconds int
-	ExternalID      string
-	MFAMessage      string
-	MFASerial       string
-	Policy          string
-	PolicyARNs      []string
-	RoleARN         string
-	RoleSessionName string
-	SourceProfile   string
-	YubikeySlot     string
+	DurationSeconds   int
+	Endpoint          Endpoint
+	ExternalID        string
+	MFAMessage        string
+	MFASerial         string
+	Policy            string
+	PolicyARNs        []string
+	RoleARN           string
+	RoleSessionName   string
+	SourceIdentity    string
+	SourceProfile     string
+	Tags              []Tag
+	TransitiveTagKeys []string
+	YubikeySlot       string
 }
 
 type Session struct {
 	DurationSeconds int
+	Endpoint        Endpoint
 	MFAMessage      string
 	MFASerial       string
 	SourceProfile   string
@@ -55,10 +98,25 @@ type Session struct {
 
 type Federate struct {
 	DurationSeconds int
+	Endpoint        Endpoint
 	SourceProfile   string
 	Name            string
 	Policy          string
 	PolicyARNs      []string
+	Tags            []Tag
+}
+
+type SAML struct {
+	AssertionCommand string
+	AssertionFile    string
+	DurationSeconds  int
+	Endpoint         Endpoint
+	IdPURL           string
+	ListenAddress    string
+	Policy           string
+	PolicyARNs       []string
+	PrincipalARN     string
+	RoleARN          string
 }
 
 // sectionAsUser takes the given ini.Section and converts it to a User if all
@@ -94,6 +152,7 @@ func sectionAsRole(section *ini.Section) (*Role, error) {
 		MFASerial:       section.Key("mfa_serial").Value(),
 		RoleARN:         section.Key("role_arn").Value(),
 		RoleSessionName: section.Key("role_session_name").Value(),
+		SourceIdentity:  section.Key("source_identity").Value(),
 		SourceProfile:   section.Key("source_profile").Value(),
 		YubikeySlot:     section.Key("yubikey_slot").Value(),
 	}
@@ -106,13 +165,39 @@ func sectionAsRole(section *ini.Section) (*Role, error) {
 		return nil, nil
 	}
 
-	// Use the given duration, or fall back to a 1 hour default.
-	if duration, err := section.Key("duration_seconds").Int(); err == nil {
+	// Parse and validate the session tags, and source identity.
+	tags, err := ParseTags(section.Key("tags").Strings(",")...)
+	if err != nil {
+		return nil, err
+	}
+	role.Tags = tags
+	if keys := section.Key("transitive_tag_keys").Strings(","); len(keys) > 0 {
+		role.TransitiveTagKeys = keys
+	}
+
+	if err := ValidateTags(role.Tags, role.TransitiveTagKeys); err != nil {
+		return nil, err
+	}
+	if err := ValidateSourceIdentity(role.SourceIdentity); err != nil {
+		return nil, err
+	}
+
+	// Use the given duration, the longest allowed duration, or fall back
+	// to a 1 hour default.
+	if section.Key("duration_seconds").Value() == "max" {
+		role.DurationSeconds = MaxDuration
+	} else if duration, err := section.Key("duration_seconds").Int(); err == nil {
 		role.DurationSeconds = duration
 	} else {
 		role.DurationSeconds = 3600 // 1 hour
 	}
 
+	// The longest allowed duration is found by retrying with shorter ones,
+	// but an MFA code can only be used once.
+	if role.DurationSeconds == MaxDuration && role.MFASerial != "" {
+		return nil, keyError{"duration_seconds", fmt.Errorf("max can not be used with mfa_serial, set the role's maximum session duration in seconds instead")}
+	}
+
 	// Read, parse, and combine the referenced policies.
 	policyARNs, policy, err := loadPolicies(section.Key("policies").Strings(",")...)
 	if err != nil {
@@ -143,6 +228,16 @@ func sectionAsFederate(section *ini.Section) (*Federate, error) {
 		return nil, nil
 	}
 
+	// Parse and validate the session tags.
+	tags, err := ParseTags(section.Key("tags").Strings(",")...)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateTags(tags, nil); err != nil {
+		return nil, err
+	}
+	federate.Tags = tags
+
 	// Use the given duration, or fall back to a 1 hour default.
 	if duration, err := section.Key("duration_seconds").Int(); err == nil {
 		federate.DurationSeconds = duration
@@ -161,6 +256,49 @@ func sectionAsFederate(section *ini.Section) (*Federate, error) {
 	return &federate, nil
 }
 
+// sectionAsSAML takes the given ini.Section and converts it to a SAML if all
+// of the required fields are present.
+func sectionAsSAML(section *ini.Section) (*SAML, error) {
+	// Pack section values into struct.
+	saml := SAML{
+		AssertionCommand: section.Key("saml_assertion_command").Value(),
+		AssertionFile:    section.Key("saml_assertion_file").Value(),
+		IdPURL:           section.Key("saml_idp_url").Value(),
+		ListenAddress:    section.Key("saml_listen_address").Value(),
+		PrincipalARN:     section.Key("principal_arn").Value(),
+		RoleARN:          section.Key("role_arn").Value(),
+	}
+
+	// Verify that required fields are present. An assertion can come from
+	// an IdP login, a file, or a command, but at least one is needed.
+	switch {
+	case saml.IdPURL == "" && saml.AssertionFile == "" && saml.AssertionCommand == "":
+		return nil, nil
+	case saml.RoleARN != "" && saml.PrincipalARN == "":
+		return nil, fmt.Errorf("principal_arn is required when role_arn is set")
+	}
+
+	// Use the given duration, the longest allowed duration, or fall back
+	// to a 1 hour default.
+	if section.Key("duration_seconds").Value() == "max" {
+		saml.DurationSeconds = MaxDuration
+	} else if duration, err := section.Key("duration_seconds").Int(); err == nil {
+		saml.DurationSeconds = duration
+	} else {
+		saml.DurationSeconds = 3600 // 1 hour
+	}
+
+	// Read, parse, and combine the referenced policies.
+	policyARNs, policy, err := loadPolicies(section.Key("policies").Strings(",")...)
+	if err != nil {
+		return nil, err
+	}
+
+	saml.PolicyARNs = policyARNs
+	saml.Policy = policy
+	return &saml, nil
+}
+
 // sectionAsSession takes the given ini.Section and converts it to a Session if
 // all of the required fields are present.
 func sectionAsSession(section *ini.Section) *Session {
@@ -188,81 +326,335 @@ func sectionAsSession(section *ini.Section) *Session {
 	}
 }
 
+// Profiles returns the names of every profile defined in the AWS
+// config/credentials files, in sorted order.
+func (c *Config) Profiles() []string {
+	seen := make(map[string]struct{})
+	for _, section := range c.credentials.SectionStrings() {
+		if section != ini.DefaultSection {
+			seen[section] = struct{}{}
+		}
+	}
+
+	// Sections in the config file that are not prefixed with "profile " are
+	// not profiles, except for the "default" profile.
+	for _, section := range c.config.SectionStrings() {
+		if section == "default" || strings.HasPrefix(section, "profile ") {
+			seen[profileName(section)] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Match returns the names of every profile matching any of the given glob
+// patterns, in sorted order.
+// "prod-*" → "prod-admin", "prod-readonly"
+func (c *Config) Match(patterns ...string) ([]string, error) {
+	var matched []string
+	for _, name := range c.Profiles() {
+		for _, pattern := range patterns {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+			}
+			if ok {
+				matched = append(matched, name)
+				break
+			}
+		}
+	}
+
+	return matched, nil
+}
+
 // Profile finds the named profile, and returns only one of either:
 // User - Contains credentials.
 // Role - Describes how to derive credentials using assume-role.
 // Session - Describes how to derive credentials using get-session-token.
 // Federate - Describes how to derive credentials using get-federation-token.
+// SAML - Describes how to derive credentials using assume-role-with-saml.
 // In the event that the named profile does not exist (or is otherwise
 // misconfigured), an error is returned.
-func (c *Config) Profile(name string) (*User, *Role, *Session, *Federate, error) {
-	section, found := c.profile(name)
+func (c *Config) Profile(name string) (*User, *Role, *Session, *Federate, *SAML, error) {
+	section, err := c.resolve(name)
+	switch {
+	case err == ErrUnknownProfile:
+		// Section is missing altogether.
+		return nil, nil, nil, nil, nil, err
+	case err != nil:
+		// Section inheritance or substitution failed.
+		return nil, nil, nil, nil, nil, c.sourceError(name, err)
+	}
 
-	// Section is missing altogether.
-	if !found {
-		return nil, nil, nil, nil, fmt.Errorf("unknown profile")
+	// Every kind of profile can specify which endpoint to use.
+	endpoint, err := c.sectionAsEndpoint(section)
+	if err != nil {
+		return nil, nil, nil, nil, nil, c.sourceError(name, err)
 	}
 
 	// Section contains a User config.
 	if user := sectionAsUser(section); user != nil {
-		return user, nil, nil, nil, nil
+		user.Endpoint = endpoint
+		return user, nil, nil, nil, nil, nil
+	}
+
+	// Section contains a SAML config. This check must be done before the
+	// check for a Role, as a SAML config can also name a role_arn.
+	if saml, err := sectionAsSAML(section); err != nil {
+		// SAML configuration was somehow invalid.
+		return nil, nil, nil, nil, nil, c.sourceError(name, err)
+	} else if saml != nil {
+		saml.Endpoint = endpoint
+		return nil, nil, nil, nil, saml, nil
 	}
 
 	// Section contains a Federate config.
 	if federate, err := sectionAsFederate(section); err != nil {
 		// Federate configuration was somehow invalid.
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, c.sourceError(name, err)
 	} else if federate != nil {
-		return nil, nil, nil, federate, nil
+		federate.Endpoint = endpoint
+		return nil, nil, nil, federate, nil, nil
 	}
 
 	// Section contains a Role config.
 	if role, err := sectionAsRole(section); err != nil {
 		// Role configuration was somehow invalid.
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, c.sourceError(name, err)
 	} else if role != nil {
-		return nil, role, nil, nil, nil
+		role.Endpoint = endpoint
+		return nil, role, nil, nil, nil, nil
 	}
 
 	// Section contains a Session config. This check must be done after the
 	// check for a Role, as a Role config is also a valid Session config.
 	if session := sectionAsSession(section); session != nil {
-		return nil, nil, session, nil, nil
+		session.Endpoint = endpoint
+		return nil, nil, session, nil, nil, nil
 	}
 
 	// Section doesn't contain any valid configs.
-	return nil, nil, nil, nil, fmt.Errorf("invalid profile")
+	return nil, nil, nil, nil, nil, c.sourceError(name, fmt.Errorf("invalid profile"))
+}
+
+// sourceError annotates the given error with the files that the named profile
+// was defined in.
+func (c *Config) sourceError(name string, err error) error {
+	if paths := c.sources[name]; len(paths) > 0 {
+		return profileError{
+			err:   err,
+			paths: paths,
+		}
+	}
+	return err
+}
+
+// profileError is an error for a profile, along with the files that the
+// profile was defined in.
+type profileError struct {
+	err   error
+	paths []string
+}
+
+func (e profileError) Error() string {
+	return fmt.Sprintf("%v (defined in %s)", e.err, strings.Join(e.paths, ", "))
+}
+
+func (e profileError) Unwrap() error {
+	return e.err
+}
+
+// keyError is an error for the value of a single key within a profile.
+type keyError struct {
+	key string
+	err error
+}
+
+func (e keyError) Error() string {
+	return fmt.Sprintf("%s: %v", e.key, e.err)
+}
+
+func (e keyError) Unwrap() error {
+	return e.err
+}
+
+// Endpoint finds the named profile, and returns the Endpoint that API calls
+// for that profile should be made against.
+func (c *Config) Endpoint(name string) (Endpoint, error) {
+	section, err := c.resolve(name)
+	switch {
+	case err == ErrUnknownProfile:
+		return Endpoint{}, err
+	case err != nil:
+		return Endpoint{}, c.sourceError(name, err)
+	}
+
+	endpoint, err := c.sectionAsEndpoint(section)
+	if err != nil {
+		return Endpoint{}, c.sourceError(name, err)
+	}
+
+	return endpoint, nil
+}
+
+// sectionAsEndpoint takes the given ini.Section and converts it to an
+// Endpoint. Since all fields are optional, a zero-value Endpoint is valid.
+func (c *Config) sectionAsEndpoint(section *ini.Section) (Endpoint, error) {
+	// Pack section values into struct.
+	// https://docs.aws.amazon.com/sdkref/latest/guide/settings-reference.html
+	endpoint := Endpoint{
+		CABundle:             section.Key("ca_bundle").Value(),
+		EndpointURL:          section.Key("endpoint_url").Value(),
+		Region:               section.Key("region").Value(),
+		STSRegionalEndpoints: section.Key("sts_regional_endpoints").Value(),
+	}
+
+	// Verify that enumerated fields have a known value.
+	switch endpoint.STSRegionalEndpoints {
+	case "", "legacy", "regional":
+	default:
+		return Endpoint{}, fmt.Errorf("invalid sts_regional_endpoints value %q", endpoint.STSRegionalEndpoints)
+	}
+
+	// Parse boolean fields, which default to false when not present.
+	for name, field := range map[string]*bool{
+		"use_dualstack_endpoint": &endpoint.UseDualStackEndpoint,
+		"use_fips_endpoint":      &endpoint.UseFIPSEndpoint,
+	} {
+		if !section.HasKey(name) {
+			continue
+		}
+		value, err := section.Key(name).Bool()
+		if err != nil {
+			return Endpoint{}, fmt.Errorf("invalid %s value %q", name, section.Key(name).Value())
+		}
+		*field = value
+	}
+
+	// A named services section can override the endpoint used for STS, and
+	// the AWS Console federation endpoint used for signing in.
+	// [services example]
+	// sts =
+	//   endpoint_url = http://localhost:4566
+	// signin =
+	//   endpoint_url = http://localhost:4566
+	if name := section.Key("services").Value(); name != "" {
+		services, err := c.config.GetSection("services " + name)
+		if err != nil {
+			return Endpoint{}, fmt.Errorf("unknown services section %q", name)
+		}
+
+		for service, field := range map[string]*string{
+			"sts":    &endpoint.EndpointURL,
+			"signin": &endpoint.SigninURL,
+		} {
+			for _, nested := range services.Key(service).NestedValues() {
+				parts := strings.SplitN(nested, "=", 2)
+				if len(parts) == 2 && strings.TrimSpace(parts[0]) == "endpoint_url" {
+					*field = strings.TrimSpace(parts[1])
+				}
+			}
+		}
+	}
+
+	return endpoint, nil
 }
 
 // profile looks up the given section name from the AWS config/credentials
-// file, following the rules for section naming and precedence in those files.
+// files, following the rules for section naming and precedence in those
+// files. If the profile is present in both files, the keys from each are
+// merged into a single section, with the credentials file taking precedence
+// for any key defined in both.
 func (c *Config) profile(name string) (*ini.Section, bool) {
 	// Look up the profile name directly in the credentials file.
-	if section, err := c.credentials.GetSection(name); err == nil {
-		return section, true
+	credentialsSection, credentialsErr := c.credentials.GetSection(name)
+
+	// Look up the profile name in the config file.
+	configSection, found := c.configProfile(name)
+
+	switch {
+	case credentialsErr != nil && !found:
+		// Section is missing from both files.
+		return nil, false
+	case credentialsErr != nil:
+		// Section is only present in the config file.
+		return configSection, true
+	case !found:
+		// Section is only present in the credentials file.
+		return credentialsSection, true
+	}
+
+	// Section is present in both files, so merge the two together. Keys from
+	// the config file are copied first, so that keys from the credentials
+	// file overwrite them.
+	merged, _ := ini.Empty().NewSection(name)
+	for _, section := range []*ini.Section{configSection, credentialsSection} {
+		for _, key := range section.Keys() {
+			merged.Key(key.Name()).SetValue(key.Value())
+		}
 	}
 
-	// In the config file, profile names are prefixed with "profile ", except
-	// for the "default" profile which is used verbatim.
-	// "default" → "default"
+	return merged, true
+}
+
+// configProfile looks up the given section name from the AWS config file.
+func (c *Config) configProfile(name string) (*ini.Section, bool) {
+	// In the config file, profile names are prefixed with "profile ". The
+	// "default" profile may be used verbatim, but if both "[default]" and
+	// "[profile default]" are present the prefixed one takes precedence.
+	// "default" → "profile default", or "default"
 	// "example" → "profile example"
-	if name != "default" {
-		name = "profile " + name
+	if section, err := c.config.GetSection("profile " + name); err == nil {
+		return section, true
 	}
 
-	// Look up the (possibly prefixed) profile name in the config file.
-	if section, err := c.config.GetSection(name); err == nil {
-		return section, true
+	if name == "default" {
+		if section, err := c.config.GetSection(name); err == nil {
+			return section, true
+		}
 	}
 
 	return nil, false
 }
 
+// Load finds, loads, and parses the AWS config/credentials files. An error is
+// returned if there are no profiles at all.
 func Load() (*Config, error) {
-	// Determine the location of the AWS config file.
-	configFile := filepath.Join(userHomeDir(), ".aws", "config")
+	cfg := load()
+
+	// If both files are completely empty, then error as there's nothing to do.
+	if len(cfg.config.Sections()) == 0 && len(cfg.credentials.Sections()) == 0 {
+		return nil, fmt.Errorf("configuration files failed to load or were empty")
+	}
+
+	// Included files that could not be found would otherwise silently drop
+	// the profiles defined within them.
+	if len(cfg.includeErrors) > 0 {
+		paths := make([]string, 0, len(cfg.includeErrors))
+		for path := range cfg.includeErrors {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		return nil, fmt.Errorf("%s: %v", paths[0], cfg.includeErrors[paths[0]][0])
+	}
+
+	return cfg, nil
+}
+
+// load finds, loads, and parses the AWS config/credentials files.
+func load() *Config {
+	// Determine the location of the AWS config files. Multiple files can be
+	// given as a list, separated in the same way as $PATH.
+	configFiles := []string{filepath.Join(userHomeDir(), ".aws", "config")}
 	if path := os.Getenv(EnvVarAWSConfigFile); path != "" {
-		configFile = path
+		configFiles = filepath.SplitList(path)
 	}
 
 	// Determine the location of the AWS credentials file.
@@ -271,16 +663,73 @@ func Load() (*Config, error) {
 		credentialsFile = path
 	}
 
-	// Parse both the config and credentials file. Since both files are
-	// optional, ignore errors if either fails to load/parse.
+	// Find every config file, along with any files they include, and any
+	// drop-in files. Earlier config files take precedence over later ones.
+	// Nested values are allowed, as they are used by the services section.
+	options := ini.LoadOptions{AllowNestedValues: true}
+	configLoader := newLoader(options)
+	for _, path := range configFiles {
+		configLoader.add(path, true)
+	}
+	credentialsLoader := newLoader(options)
+	credentialsLoader.add(credentialsFile, false)
+
+	// Parse both the config and credentials files. Since all files are
+	// optional, ignore errors if any fail to load/parse.
 	var cfg Config
-	if file, err := ini.Load(configFile); err == nil {
+	if file, err := configLoader.load(); err == nil {
 		cfg.config = file
 	}
-	if file, err := ini.Load(credentialsFile); err == nil {
+	if file, err := credentialsLoader.load(); err == nil {
 		cfg.credentials = file
 	}
 
+	// Remember which files each profile came from, with the credentials file
+	// first as it takes precedence.
+	cfg.sources = credentialsLoader.sources
+	for name, paths := range configLoader.sources {
+		cfg.sources[name] = append(cfg.sources[name], paths...)
+	}
+
+	// Remember which file each key of each profile came from, following the
+	// same precedence as profile, so that relative paths can be resolved
+	// against the right directory.
+	cfg.keySources = make(map[string]map[string]string)
+	for section, keys := range configLoader.keys {
+		name := profileName(section)
+		switch {
+		case section == name && name != "default":
+			// Not a profile section.
+			continue
+		case section == "default" && configLoader.keys["profile default"] != nil:
+			// Shadowed by the "[profile default]" section.
+			continue
+		}
+		cfg.keySources[name] = keys
+	}
+	for name, keys := range credentialsLoader.keys {
+		merged := make(map[string]string, len(keys))
+		for key, path := range cfg.keySources[name] {
+			merged[key] = path
+		}
+		for key, path := range keys {
+			merged[key] = path
+		}
+		cfg.keySources[name] = merged
+	}
+
+	// Remember which files were loaded, and which failed, for validation.
+	cfg.configFiles = configLoader.files
+	cfg.credentialsFiles = credentialsLoader.files
+	cfg.failed = configLoader.failed
+	for path, err := range credentialsLoader.failed {
+		cfg.failed[path] = err
+	}
+	cfg.includeErrors = configLoader.includeErrors
+	for path, errs := range credentialsLoader.includeErrors {
+		cfg.includeErrors[path] = errs
+	}
+
 	// Replace nil files with non-nil, empty files for ease of use.
 	if cfg.config == nil {
 		cfg.config = ini.Empty()
@@ -297,12 +746,7 @@ func Load() (*Config, error) {
 		cfg.credentials.DeleteSection(ini.DefaultSection)
 	}
 
-	// If both files are completely empty, then error as there's nothing to do.
-	if len(cfg.config.Sections()) == 0 && len(cfg.credentials.Sections()) == 0 {
-		return nil, fmt.Errorf("configuration files failed to load or were empty")
-	}
-
-	return &cfg, nil
+	return &cfg
 }
 
 // userHomeDir returns the home directory for the user the process is
@@ -334,7 +778,10 @@ func loadPolicies(policyRefs ...string) ([]string, string, error) {
 		} else {
 			document, err := ioutil.ReadFile(policyRef)
 			if err != nil {
-				return nil, "", err
+				return nil, "", keyError{"policies", err}
+			}
+			if err := json.Unmarshal(document, new(interface{})); err != nil {
+				return nil, "", keyError{"policies", fmt.Errorf("%s is not valid JSON: %v", policyRef, err)}
 			}
 			documents = append(documents, document)
 		}
@@ -7,6 +7,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -71,3 +74,754 @@ func TestLoad(t *testing.T) {
 		})
 	}
 }
+
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		profile  string
+		endpoint Endpoint
+		err      bool
+	}{
+		{
+			profile: "default",
+			endpoint: Endpoint{
+				Region: "us-west-2",
+			},
+		},
+		{
+			profile: "regional",
+			endpoint: Endpoint{
+				Region:               "eu-west-1",
+				STSRegionalEndpoints: "regional",
+				UseFIPSEndpoint:      true,
+			},
+		},
+		{
+			profile: "local",
+			endpoint: Endpoint{
+				EndpointURL: "http://localhost:4566",
+				SigninURL:   "http://localhost:4567",
+			},
+		},
+		{
+			profile: "bad-regional",
+			err:     true,
+		},
+		{
+			profile: "bad-services",
+			err:     true,
+		},
+		{
+			profile: "missing",
+			err:     true,
+		},
+	}
+
+	os.Clearenv()
+	os.Setenv("HOME", "testdata/endpoints")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			endpoint, err := cfg.Endpoint(test.profile)
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if endpoint != test.endpoint {
+				t.Fatalf("expected endpoint %+v but got %+v", test.endpoint, endpoint)
+			}
+		})
+	}
+}
+
+func TestProfileMerge(t *testing.T) {
+	tests := []struct {
+		profile string
+		user    User
+	}{
+		{
+			profile: "default",
+			user: User{
+				AWSAccessKeyID:     "default-key",
+				AWSSecretAccessKey: "default-secret",
+				Endpoint: Endpoint{
+					Region: "us-west-2",
+				},
+			},
+		},
+		{
+			profile: "dev",
+			user: User{
+				AWSAccessKeyID:     "credentials-key",
+				AWSSecretAccessKey: "credentials-secret",
+				Endpoint: Endpoint{
+					Region: "eu-west-1",
+				},
+			},
+		},
+	}
+
+	os.Clearenv()
+	os.Setenv("HOME", "testdata/merged")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			user, _, _, _, _, err := cfg.Profile(test.profile)
+			switch {
+			case err != nil:
+				t.Fatalf("expected no error but got error %q", err)
+			case user == nil:
+				t.Fatalf("expected a user but got none")
+			case *user != test.user:
+				t.Fatalf("expected user %+v but got %+v", test.user, *user)
+			}
+		})
+	}
+}
+
+func TestLoadIncludes(t *testing.T) {
+	tests := []struct {
+		env     map[string]string
+		profile string
+		region  string
+		err     bool
+	}{
+		{
+			profile: "main",
+			region:  "us-east-1",
+		},
+		{
+			profile: "overridden",
+			region:  "us-east-1",
+		},
+		{
+			profile: "included",
+			region:  "eu-west-1",
+		},
+		{
+			profile: "dropin",
+			region:  "ca-central-1",
+		},
+		{
+			profile: "extra",
+			err:     true,
+		},
+		{
+			env: map[string]string{
+				EnvVarAWSConfigFile: "testdata/includes/.aws/extra" + string(os.PathListSeparator) + "testdata/includes/.aws/config",
+			},
+			profile: "main",
+			region:  "sa-east-1",
+		},
+		{
+			env: map[string]string{
+				EnvVarAWSConfigFile: "testdata/includes/.aws/config" + string(os.PathListSeparator) + "testdata/includes/.aws/extra",
+			},
+			profile: "extra",
+			region:  "sa-east-1",
+		},
+		{
+			env: map[string]string{
+				EnvVarAWSConfigFile: "testdata/includes/.aws/undefined",
+			},
+			profile: "undefined",
+			err:     true,
+		},
+		{
+			env: map[string]string{
+				EnvVarAWSConfigFile: "testdata/includes/.aws/dangling",
+			},
+			profile: "dangling",
+			err:     true,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			// Reset environment and recreate it for every test.
+			os.Clearenv()
+			os.Setenv("HOME", "testdata/includes")
+			for key, value := range test.env {
+				os.Setenv(key, value)
+			}
+
+			cfg, err := Load()
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			}
+
+			endpoint, err := cfg.Endpoint(test.profile)
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if endpoint.Region != test.region {
+				t.Fatalf("expected region %q but got %q", test.region, endpoint.Region)
+			}
+		})
+	}
+}
+
+func TestProfileInherit(t *testing.T) {
+	tests := []struct {
+		profile string
+		role    Role
+		err     bool
+	}{
+		{
+			profile: "dev",
+			role: Role{
+				DurationSeconds: 3600,
+				Endpoint:        Endpoint{Region: "us-west-2"},
+				RoleARN:         "arn:aws:iam::000000000000:role/admin",
+				RoleSessionName: "admin-session",
+				SourceProfile:   "default",
+			},
+		},
+		{
+			profile: "prod",
+			role: Role{
+				DurationSeconds: 3600,
+				Endpoint:        Endpoint{Region: "us-east-1"},
+				RoleARN:         "arn:aws:iam::111111111111:role/readonly",
+				RoleSessionName: "readonly-session",
+				SourceProfile:   "default",
+			},
+		},
+		{
+			profile: "undefined",
+			role: Role{
+				DurationSeconds: 3600,
+				Endpoint:        Endpoint{Region: "us-west-2"},
+				RoleARN:         "arn:aws:iam::${account_id}:role/admin",
+				RoleSessionName: "admin-session",
+				SourceProfile:   "default",
+			},
+		},
+		{
+			profile: "cycle-a",
+			err:     true,
+		},
+		{
+			profile: "orphan",
+			err:     true,
+		},
+		{
+			profile: "self-reference",
+			err:     true,
+		},
+	}
+
+	os.Clearenv()
+	os.Setenv("HOME", "testdata/inherit")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			_, role, _, _, _, err := cfg.Profile(test.profile)
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			case role == nil:
+				t.Fatalf("expected a role but got none")
+			}
+
+			if !reflect.DeepEqual(*role, test.role) {
+				t.Fatalf("expected role %+v but got %+v", test.role, *role)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		expected []string
+		err      bool
+	}{
+		{
+			patterns: []string{"*"},
+			expected: []string{"cycle-a", "cycle-b", "default", "dev", "orphan", "prod", "self-reference", "team-defaults", "undefined"},
+		},
+		{
+			patterns: []string{"cycle-*"},
+			expected: []string{"cycle-a", "cycle-b"},
+		},
+		{
+			patterns: []string{"prod", "dev", "d*"},
+			expected: []string{"default", "dev", "prod"},
+		},
+		{
+			patterns: []string{"missing"},
+		},
+		{
+			patterns: []string{"["},
+			err:      true,
+		},
+	}
+
+	os.Clearenv()
+	os.Setenv("HOME", "testdata/inherit")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			actual, err := cfg.Match(test.patterns...)
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("expected profiles %v but got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	tests := []struct {
+		values   map[string]string
+		expected map[string]string
+		err      bool
+	}{
+		{
+			values: map[string]string{
+				"account_id": "000000000000",
+				"role_arn":   "arn:aws:iam::${account_id}:role/admin",
+			},
+			expected: map[string]string{
+				"account_id": "000000000000",
+				"role_arn":   "arn:aws:iam::000000000000:role/admin",
+			},
+		},
+		{
+			values: map[string]string{
+				"credential_process": "/usr/local/bin/creds --cache ${HOME}/.cache",
+			},
+			expected: map[string]string{
+				"credential_process": "/usr/local/bin/creds --cache ${HOME}/.cache",
+			},
+		},
+		{
+			values: map[string]string{
+				"mfa_message": "Code for ${USER} on ${name}",
+				"name":        "prod",
+			},
+			expected: map[string]string{
+				"mfa_message": "Code for ${USER} on prod",
+				"name":        "prod",
+			},
+		},
+		{
+			values: map[string]string{
+				"a": "${b}",
+				"b": "${a}",
+			},
+			err: true,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			actual, err := substitute(test.values)
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("expected values %v but got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestProfileExpand(t *testing.T) {
+	const (
+		readOnly = `{"Version":"2012-10-17","Statement":[{"Action":"s3:GetObject","Effect":"Allow","Resource":"*"}]}`
+		deny     = `{"Version":"2012-10-17","Statement":[{"Action":"s3:DeleteObject","Effect":"Deny","Resource":"*"},{"Action":"s3:DeleteObject","Effect":"Deny","Resource":"*"}]}`
+	)
+
+	tests := []struct {
+		profile    string
+		policy     string
+		policyARNs []string
+		err        bool
+	}{
+		{
+			profile: "relative",
+			policy:  readOnly,
+		},
+		{
+			profile:    "home",
+			policy:     readOnly,
+			policyARNs: []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+		},
+		{
+			profile: "env",
+			policy:  deny,
+		},
+		{
+			profile: "undefined-env",
+			err:     true,
+		},
+		{
+			profile: "missing-file",
+			err:     true,
+		},
+	}
+
+	home, err := filepath.Abs("testdata/expand")
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	os.Clearenv()
+	os.Setenv("HOME", home)
+	os.Setenv("TEAM_DIR", filepath.Join(home, "team"))
+
+	// Keys from a credentials file in another directory must not change how
+	// paths from the config file are resolved.
+	os.Setenv(EnvVarAWSSharedCredentialsFile, filepath.Join(home, "elsewhere", "credentials"))
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			_, role, _, _, _, err := cfg.Profile(test.profile)
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if role.Policy != test.policy {
+				t.Fatalf("expected policy %s but got %s", test.policy, role.Policy)
+			}
+			if !reflect.DeepEqual(role.PolicyARNs, test.policyARNs) {
+				t.Fatalf("expected policy arns %v but got %v", test.policyARNs, role.PolicyARNs)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	home, err := filepath.Abs("testdata/validate")
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	configFile := filepath.Join(home, ".aws", "config")
+	credentialsFile := filepath.Join(home, ".aws", "credentials")
+	includeFile := filepath.Join(home, ".aws", "include")
+
+	// The unreadable-policy profile names a directory, as file permissions
+	// can not be stored by git, and do not apply when running as root.
+	policiesDir := filepath.Join(home, ".aws", "policies")
+	expected := []string{
+		configFile + ":14: profile typo: unknown key rol_session_name",
+		configFile + ":18: profile malformed: malformed role_arn arn:aws:iam::role/malformed",
+		configFile + ":19: profile malformed: malformed mfa_serial 123",
+		configFile + ":20: profile malformed: duration_seconds 60 is outside of the allowed range 900-43200",
+		configFile + ":23: profile dangling: source_profile references unknown profile missing",
+		configFile + ":26: profile loop-a: recursive source_profile chain loop-a → loop-b → loop-a",
+		configFile + ":29: profile loop-b: recursive source_profile chain loop-b → loop-a → loop-b",
+		configFile + ":32: profile orphan-role: role_arn requires a source_profile",
+		configFile + ":34: section [bare] will be ignored, did you mean [profile bare]?",
+		configFile + ":40: profile missing-policy: policies: open " + filepath.Join(policiesDir, "missing.json") + ": no such file or directory",
+		configFile + ":45: profile invalid-policy: policies: " + filepath.Join(policiesDir, "invalid.json") + " is not valid JSON: invalid character 'o' in literal null (expecting 'u')",
+		configFile + ":50: profile unreadable-policy: policies: read " + policiesDir + ": is a directory",
+		configFile + ":56: profile max-mfa: duration_seconds: max can not be used with mfa_serial, set the role's maximum session duration in seconds instead",
+		credentialsFile + ":1: malformed line, expected a [section] or key = value",
+		includeFile + ":1: include ${MISSING_DIR}/*.ini: undefined environment variable $MISSING_DIR",
+		includeFile + ":1: include missing.ini: file " + filepath.Join(home, ".aws", "missing.ini") + " does not exist",
+	}
+
+	os.Clearenv()
+	os.Setenv("HOME", home)
+	os.Setenv(EnvVarAWSConfigFile, configFile+string(os.PathListSeparator)+includeFile)
+	problems, err := Validate()
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	var actual []string
+	for _, problem := range problems {
+		actual = append(actual, problem.String())
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected problems:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	tests := []struct {
+		tags       []string
+		transitive []string
+		err        bool
+	}{
+		{},
+		{
+			tags:       []string{"team=platform", "env=prod"},
+			transitive: []string{"Team"},
+		},
+		{
+			tags: []string{"team"},
+			err:  true,
+		},
+		{
+			tags: []string{"team=platform", "Team=infra"},
+			err:  true,
+		},
+		{
+			tags: []string{"aws:team=platform"},
+			err:  true,
+		},
+		{
+			tags: []string{"team=platform!"},
+			err:  true,
+		},
+		{
+			tags: []string{"=platform"},
+			err:  true,
+		},
+		{
+			tags:       []string{"team=platform"},
+			transitive: []string{"env"},
+			err:        true,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			tags, err := ParseTags(test.tags...)
+			if err == nil {
+				err = ValidateTags(tags, test.transitive)
+			}
+
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	home, err := filepath.Abs("testdata/validate")
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	tests := []struct {
+		name       string
+		suggestion string
+	}{
+		{
+			name:       "orphan-rol",
+			suggestion: "orphan-role",
+		},
+		{
+			name:       "Typo",
+			suggestion: "typo",
+		},
+		{
+			name: "something-else",
+		},
+	}
+
+	os.Clearenv()
+	os.Setenv("HOME", home)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			if suggestion := cfg.Suggest(test.name); suggestion != test.suggestion {
+				t.Fatalf("expected suggestion %q but got %q", test.suggestion, suggestion)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		profile string
+		summary Summary
+		err     bool
+	}{
+		{
+			profile: "user",
+			summary: Summary{Name: "user", Type: TypeUser},
+		},
+		{
+			profile: "role",
+			summary: Summary{
+				Name:          "role",
+				Type:          TypeRole,
+				SourceProfile: "user",
+				RoleARN:       "arn:aws:iam::111111111111:role/team/admin",
+				MFASerial:     "arn:aws:iam::000000000000:mfa/user",
+			},
+		},
+		{
+			profile: "session",
+			summary: Summary{
+				Name:          "session",
+				Type:          TypeSession,
+				SourceProfile: "user",
+				MFASerial:     "arn:aws:iam::000000000000:mfa/user",
+			},
+		},
+		{
+			profile: "federate",
+			summary: Summary{Name: "federate", Type: TypeFederate, SourceProfile: "user"},
+		},
+		{
+			profile: "saml",
+			summary: Summary{Name: "saml", Type: TypeSAML, RoleARN: "arn:aws:iam::222222222222:role/sso"},
+		},
+		{
+			profile: "invalid",
+			summary: Summary{Name: "invalid"},
+			err:     true,
+		},
+		{
+			profile: "missing",
+			summary: Summary{Name: "missing"},
+			err:     true,
+		},
+	}
+
+	os.Clearenv()
+	os.Setenv("HOME", "testdata/summary")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			summary := cfg.Summarize(test.profile)
+			switch {
+			case summary.Err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", summary.Err)
+			case summary.Err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			summary.Err = nil
+			if summary != test.summary {
+				t.Fatalf("expected summary %+v but got %+v", test.summary, summary)
+			}
+		})
+	}
+}
+
+func TestSummaryARN(t *testing.T) {
+	tests := []struct {
+		arn       string
+		accountID string
+		roleName  string
+	}{
+		{
+			arn:       "arn:aws:iam::000000000000:user/alice",
+			accountID: "000000000000",
+		},
+		{
+			arn:       "arn:aws:iam::000000000000:role/admin",
+			accountID: "000000000000",
+			roleName:  "admin",
+		},
+		{
+			arn:       "arn:aws:iam::000000000000:role/team/admin",
+			accountID: "000000000000",
+			roleName:  "admin",
+		},
+		{
+			arn:       "arn:aws:sts::000000000000:assumed-role/admin/session",
+			accountID: "000000000000",
+			roleName:  "admin",
+		},
+		{
+			arn:       "arn:aws:iam::000000000000:role/",
+			accountID: "000000000000",
+		},
+		{
+			arn:       "arn:aws:iam::000000000000:role",
+			accountID: "000000000000",
+		},
+		{
+			arn: "arn:aws:iam::000000000000",
+		},
+		{
+			arn: "not:an:arn::000000000000:role/admin",
+		},
+		{
+			arn: "",
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			summary := Summary{RoleARN: test.arn}
+			if accountID := summary.AccountID(); accountID != test.accountID {
+				t.Fatalf("expected account id %q but got %q", test.accountID, accountID)
+			}
+			if roleName := summary.RoleName(); roleName != test.roleName {
+				t.Fatalf("expected role name %q but got %q", test.roleName, roleName)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pathKeys are the keys whose values are file paths (or lists of file paths)
+// which are expanded before use.
+var pathKeys = map[string]bool{
+	"ca_bundle":               true,
+	"policies":                true,
+	"saml_assertion_file":     true,
+	"web_identity_token_file": true,
+}
+
+// expandPaths expands every file path in the given value for the given key.
+// Most keys hold a single path, but the policies key holds a comma separated
+// list of paths and policy ARNs.
+func expandPaths(key, value, dir string) (string, error) {
+	if key != "policies" {
+		return expandPath(value, dir)
+	}
+
+	var refs []string
+	for _, ref := range strings.Split(value, ",") {
+		ref = strings.TrimSpace(ref)
+
+		// Policy ARNs are not paths, and are left as-is.
+		if ref != "" && !strings.HasPrefix(ref, "arn:aws:iam:") {
+			expanded, err := expandPath(ref, dir)
+			if err != nil {
+				return "", err
+			}
+			ref = expanded
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return strings.Join(refs, ","), nil
+}
+
+// expandPath expands a leading "~" to the user's home directory, and any
+// $VAR or ${VAR} references to the value of that environment variable. If
+// the resulting path is relative, it is resolved against the given directory.
+// "~/policies/ro.json" → "/home/user/policies/ro.json"
+// "$TEAM_DIR/x.json"   → "/opt/team/x.json"
+// "policies/ro.json"   → "/home/user/.aws/policies/ro.json"
+func expandPath(path, dir string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	// Expand the user's home directory.
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		path = userHomeDir() + path[1:]
+	}
+
+	// Expand environment variables, erroring on any that are not set.
+	var err error
+	path = os.Expand(path, func(name string) string {
+		value, found := os.LookupEnv(name)
+		if !found && err == nil {
+			err = fmt.Errorf("undefined environment variable $%s", name)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Resolve relative paths against the given directory.
+	if dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	return path, nil
+}
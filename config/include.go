@@ -0,0 +1,176 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// includeKey is the key, in the "[DEFAULT]" section at the top of a config
+// file, that names other files to be loaded.
+// include = shared.ini, ~/team/*.ini
+const includeKey = "include"
+
+// loader walks a set of config files, following include directives and
+// drop-in directories, and records the order in which they take precedence.
+type loader struct {
+	options ini.LoadOptions
+
+	// files is the list of every file to load, ordered from highest to
+	// lowest precedence.
+	files []string
+
+	// sources maps each profile name to the files that define it, ordered
+	// from highest to lowest precedence.
+	sources map[string][]string
+
+	// keys maps each section name, and each of its keys, to the file that
+	// the key's value is taken from, which is the highest precedence file
+	// that defines it.
+	keys map[string]map[string]string
+
+	// failed maps files that exist, but failed to load/parse, to the error
+	// that was encountered.
+	failed map[string]error
+
+	// includeErrors maps files to the errors encountered expanding their
+	// include directives.
+	includeErrors map[string][]error
+
+	// seen tracks which files have already been visited, to avoid loading a
+	// file twice or following an include cycle.
+	seen map[string]struct{}
+}
+
+// newLoader creates a loader that parses files with the given options.
+func newLoader(options ini.LoadOptions) *loader {
+	return &loader{
+		options:       options,
+		sources:       make(map[string][]string),
+		keys:          make(map[string]map[string]string),
+		failed:        make(map[string]error),
+		includeErrors: make(map[string][]error),
+		seen:          make(map[string]struct{}),
+	}
+}
+
+// add visits the given file, followed by any files it includes and, if
+// enabled, the files in its drop-in directory. Files that fail to load/parse
+// are skipped, as all config files are optional.
+func (l *loader) add(path string, dropins bool) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	if _, found := l.seen[path]; found {
+		return
+	}
+	l.seen[path] = struct{}{}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return
+	}
+
+	file, err := ini.LoadSources(l.options, path)
+	if err != nil {
+		l.failed[path] = err
+		return
+	}
+
+	l.files = append(l.files, path)
+	for _, section := range file.Sections() {
+		name := profileName(section.Name())
+		l.sources[name] = append(l.sources[name], path)
+
+		if l.keys[section.Name()] == nil {
+			l.keys[section.Name()] = make(map[string]string)
+		}
+		for _, key := range section.KeyStrings() {
+			if _, found := l.keys[section.Name()][key]; !found {
+				l.keys[section.Name()][key] = path
+			}
+		}
+	}
+
+	// Included files take precedence just below the file that includes them.
+	// Paths are expanded, with relative paths resolved against the directory
+	// of the including file, and glob patterns are expanded in lexical order.
+	for _, include := range file.Section(ini.DefaultSection).Key(includeKey).Strings(",") {
+		expanded, err := expandPath(include, filepath.Dir(path))
+		if err != nil {
+			l.includeErrors[path] = append(l.includeErrors[path], fmt.Errorf("include %s: %v", include, err))
+			continue
+		}
+
+		// A glob pattern may match no files at all, but a literal path must
+		// name a file that exists.
+		matches, err := filepath.Glob(expanded)
+		switch {
+		case err != nil:
+			l.includeErrors[path] = append(l.includeErrors[path], fmt.Errorf("include %s: %v", include, err))
+			continue
+		case len(matches) == 0 && !strings.ContainsAny(expanded, `*?[`):
+			l.includeErrors[path] = append(l.includeErrors[path], fmt.Errorf("include %s: file %s does not exist", include, expanded))
+			continue
+		}
+
+		for _, match := range matches {
+			l.add(match, false)
+		}
+	}
+
+	if !dropins {
+		return
+	}
+
+	// Drop-in files (like ~/.aws/config.d/*.ini) take precedence below the
+	// file they belong to. Among drop-ins, files that sort lexically later
+	// take precedence, so 99-local.ini overrides 10-team.ini.
+	matches, _ := filepath.Glob(filepath.Join(path+".d", "*.ini"))
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	for _, match := range matches {
+		l.add(match, false)
+	}
+}
+
+// load parses all visited files into a single ini.File. When a key is
+// defined in multiple files, the value from the file with the highest
+// precedence is used.
+func (l *loader) load() (*ini.File, error) {
+	if len(l.files) == 0 {
+		return nil, nil
+	}
+
+	// Sources loaded later overwrite keys from those loaded earlier, so
+	// reverse the order to have the highest precedence file loaded last.
+	sources := make([]interface{}, len(l.files))
+	for index, path := range l.files {
+		sources[len(l.files)-index-1] = path
+	}
+
+	file, err := ini.LoadSources(l.options, sources[0], sources[1:]...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The include directives have been followed, and are no longer needed.
+	file.Section(ini.DefaultSection).DeleteKey(includeKey)
+
+	return file, nil
+}
+
+// profileName converts a config file section name into the name of the
+// profile that it defines.
+// "default"         → "default"
+// "profile example" → "example"
+func profileName(section string) string {
+	return strings.TrimPrefix(section, "profile ")
+}
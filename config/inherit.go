@@ -0,0 +1,174 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/ini.v1"
+)
+
+// inheritKey is the key that names a parent profile, from which any
+// unspecified keys are taken.
+// inherit = team-defaults
+const inheritKey = "inherit"
+
+// variablePattern matches variable references within a value, like the
+// "${account_id}" in "arn:aws:iam::${account_id}:role/admin".
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+
+// ErrUnknownProfile is returned when a named profile can not be found.
+var ErrUnknownProfile = fmt.Errorf("unknown profile")
+
+// resolve looks up the named profile, and returns a section containing all of
+// its keys after inheriting keys from any parent profiles, and substituting
+// any variable references.
+func (c *Config) resolve(name string) (*ini.Section, error) {
+	values, origins, err := c.inherit(name, map[string]struct{}{
+		name: {},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values, err = substitute(values)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand file paths. Relative paths are resolved against the directory
+	// of the file that each key came from.
+	for key, value := range values {
+		if !pathKeys[key] {
+			continue
+		}
+
+		expanded, err := expandPaths(key, value, c.sourceDir(origins[key], key))
+		if err != nil {
+			return nil, fmt.Errorf("%s in profile %s: %v", key, origins[key], err)
+		}
+		values[key] = expanded
+	}
+
+	// Pack the resolved values into a new section, so that the original
+	// section is left untouched.
+	section, _ := ini.Empty().NewSection(name)
+	for key, value := range values {
+		section.Key(key).SetValue(value)
+	}
+
+	return section, nil
+}
+
+// inherit returns the keys of the named profile, combined with any keys that
+// it does not specify from its chain of parent profiles. The name of the
+// profile that each key came from is also returned. The seen set is used to
+// detect circular inheritance.
+func (c *Config) inherit(name string, seen map[string]struct{}) (map[string]string, map[string]string, error) {
+	section, found := c.profile(name)
+	if !found {
+		return nil, nil, ErrUnknownProfile
+	}
+
+	values := make(map[string]string)
+	origins := make(map[string]string)
+	for _, key := range section.Keys() {
+		values[key.Name()] = key.Value()
+		origins[key.Name()] = name
+	}
+
+	parent := values[inheritKey]
+	if parent == "" {
+		return values, origins, nil
+	}
+
+	// Check that we have not visited this profile already, as that would mean
+	// that there is a circular inheritance (mis)configured.
+	if _, found := seen[parent]; found {
+		return nil, nil, fmt.Errorf("recursive inherit of profile %s", parent)
+	}
+	seen[parent] = struct{}{}
+
+	// Recursively follow the parent profile reference, to walk the
+	// inheritance "chain".
+	parentValues, parentOrigins, err := c.inherit(parent, seen)
+	switch {
+	case err == ErrUnknownProfile:
+		return nil, nil, fmt.Errorf("unknown inherited profile %s", parent)
+	case err != nil:
+		return nil, nil, err
+	}
+
+	// Keys specified by this profile take precedence over inherited ones.
+	for key, value := range parentValues {
+		if _, found := values[key]; !found {
+			values[key] = value
+			origins[key] = parentOrigins[key]
+		}
+	}
+
+	return values, origins, nil
+}
+
+// sourceDir returns the directory of the file that the given key of the named
+// profile was taken from, or an empty string if it is not known.
+func (c *Config) sourceDir(name, key string) string {
+	if path, found := c.keySources[name][key]; found {
+		return filepath.Dir(path)
+	}
+	return ""
+}
+
+// substitute replaces every variable reference in the given values with the
+// value of the referenced key. References may be nested, but must not be
+// circular. References that do not name another key are left as-is, so that
+// values like "${HOME}" in a credential_process still reach the shell, and
+// those in path keys can be expanded as environment variables.
+func substitute(values map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(values))
+
+	var expand func(key string, stack map[string]struct{}) (string, error)
+	expand = func(key string, stack map[string]struct{}) (string, error) {
+		if value, found := result[key]; found {
+			return value, nil
+		}
+
+		if _, found := stack[key]; found {
+			return "", fmt.Errorf("recursive variable ${%s}", key)
+		}
+		stack[key] = struct{}{}
+		defer delete(stack, key)
+
+		var err error
+		value := variablePattern.ReplaceAllStringFunc(values[key], func(match string) string {
+			name := variablePattern.FindStringSubmatch(match)[1]
+			if _, found := values[name]; !found {
+				return match
+			}
+
+			expanded, expandErr := expand(name, stack)
+			if expandErr != nil && err == nil {
+				err = expandErr
+			}
+			return expanded
+		})
+		if err != nil {
+			return "", err
+		}
+
+		result[key] = value
+		return value, nil
+	}
+
+	for key := range values {
+		if _, err := expand(key, map[string]struct{}{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,68 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package config
+
+import (
+	"strings"
+)
+
+// maxSuggestionDistance is the largest edit distance between an unknown
+// profile name and a known one, for the known one to be suggested.
+const maxSuggestionDistance = 2
+
+// Suggest returns the name of the profile that most closely matches the
+// given (presumably misspelled) name, or an empty string if no profile is a
+// close enough match.
+// "prdo" → "prod"
+func (c *Config) Suggest(name string) string {
+	var suggestion string
+	best := maxSuggestionDistance + 1
+
+	for _, profile := range c.Profiles() {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(profile)); distance < best {
+			suggestion, best = profile, distance
+		}
+	}
+
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between the given strings.
+func editDistance(a, b string) int {
+	source, target := []rune(a), []rune(b)
+
+	// Only the previous row of the distance matrix is needed to compute the
+	// current one.
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(target)]
+}
+
+// min returns the smallest of the given values.
+func min(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
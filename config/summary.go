@@ -0,0 +1,94 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package config
+
+import (
+	"strings"
+)
+
+// Profile types, as classified by Config.Profile.
+const (
+	TypeUser     = "user"
+	TypeRole     = "role"
+	TypeSession  = "session"
+	TypeFederate = "federate"
+	TypeSAML     = "saml"
+)
+
+// Summary is a brief description of a single profile, independent of which
+// type of profile it is.
+type Summary struct {
+	Name          string
+	Type          string
+	SourceProfile string
+	RoleARN       string
+	MFASerial     string
+	Err           error
+}
+
+// Summarize finds the named profile, and returns a Summary of it. If the
+// profile is misconfigured, the Summary contains only the name and error.
+func (c *Config) Summarize(name string) Summary {
+	summary := Summary{
+		Name: name,
+	}
+
+	user, role, session, federate, saml, err := c.Profile(name)
+	switch {
+	case err != nil:
+		summary.Err = err
+	case user != nil:
+		summary.Type = TypeUser
+	case role != nil:
+		summary.Type = TypeRole
+		summary.SourceProfile = role.SourceProfile
+		summary.RoleARN = role.RoleARN
+		summary.MFASerial = role.MFASerial
+	case session != nil:
+		summary.Type = TypeSession
+		summary.SourceProfile = session.SourceProfile
+		summary.MFASerial = session.MFASerial
+	case federate != nil:
+		summary.Type = TypeFederate
+		summary.SourceProfile = federate.SourceProfile
+	case saml != nil:
+		summary.Type = TypeSAML
+		summary.RoleARN = saml.RoleARN
+	}
+
+	return summary
+}
+
+// AccountID returns the account id parsed from the role ARN, or an empty
+// string if there is no (well-formed) role ARN.
+// "arn:aws:iam::000000000000:role/admin" → "000000000000"
+func (s Summary) AccountID() string {
+	parts := strings.SplitN(s.RoleARN, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[4]
+}
+
+// RoleName returns the role name parsed from the role ARN, excluding any path
+// or session name, or an empty string if the ARN does not name a role.
+// "arn:aws:iam::000000000000:role/team/admin" → "admin"
+// "arn:aws:sts::000000000000:assumed-role/admin/session" → "admin"
+func (s Summary) RoleName() string {
+	parts := strings.SplitN(s.RoleARN, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return ""
+	}
+
+	resource := strings.Split(parts[5], "/")
+	switch {
+	case resource[0] == "role" && len(resource) >= 2:
+		return resource[len(resource)-1]
+	case resource[0] == "assumed-role" && len(resource) == 3:
+		return resource[1]
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits imposed by STS on session tags and source identities.
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/id_session-tags.html#id_session-tags_know
+const (
+	maxTags           = 50
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+var (
+	// tagPattern matches the characters allowed in a tag key or value.
+	tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+	// sourceIdentityPattern matches a valid source identity.
+	sourceIdentityPattern = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+)
+
+// Tag is a single session tag, which is passed along when assuming a role or
+// getting a federation token.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// ParseTags parses the given list of "key=value" strings into a list of
+// tags, preserving their order.
+// "team=platform", "env=prod" → {team platform}, {env prod}
+func ParseTags(values ...string) ([]Tag, error) {
+	var tags []Tag
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed tag %q, expected key=value", value)
+		}
+
+		tags = append(tags, Tag{
+			Key:   strings.TrimSpace(parts[0]),
+			Value: strings.TrimSpace(parts[1]),
+		})
+	}
+
+	return tags, nil
+}
+
+// ValidateTags checks the given session tags and transitive tag keys against
+// the limits imposed by STS, so that problems are caught before making an
+// API call.
+func ValidateTags(tags []Tag, transitiveTagKeys []string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("at most %d tags can be given, but %d were", maxTags, len(tags))
+	}
+
+	// Tag keys are case-insensitive, and must be unique.
+	keys := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		switch {
+		case tag.Key == "" || utf8.RuneCountInString(tag.Key) > maxTagKeyLength:
+			return fmt.Errorf("tag key %q must be 1-%d characters", tag.Key, maxTagKeyLength)
+		case utf8.RuneCountInString(tag.Value) > maxTagValueLength:
+			return fmt.Errorf("tag value for %q must be at most %d characters", tag.Key, maxTagValueLength)
+		case !tagPattern.MatchString(tag.Key) || !tagPattern.MatchString(tag.Value):
+			return fmt.Errorf("tag %q contains characters that are not allowed", tag.Key)
+		case strings.HasPrefix(strings.ToLower(tag.Key), "aws:"):
+			return fmt.Errorf("tag key %q must not start with aws:", tag.Key)
+		}
+
+		key := strings.ToLower(tag.Key)
+		if _, found := keys[key]; found {
+			return fmt.Errorf("tag key %q was given more than once", tag.Key)
+		}
+		keys[key] = struct{}{}
+	}
+
+	// Transitive tag keys must name one of the given tags.
+	for _, key := range transitiveTagKeys {
+		if _, found := keys[strings.ToLower(key)]; !found {
+			return fmt.Errorf("transitive tag key %q is not one of the given tags", key)
+		}
+	}
+
+	return nil
+}
+
+// ValidateSourceIdentity checks the given source identity against the limits
+// imposed by STS.
+func ValidateSourceIdentity(sourceIdentity string) error {
+	switch {
+	case sourceIdentity == "":
+		return nil
+	case !sourceIdentityPattern.MatchString(sourceIdentity):
+		return fmt.Errorf("source identity %q must be 2-64 characters from [A-Za-z0-9+=,.@_-]", sourceIdentity)
+	case strings.HasPrefix(strings.ToLower(sourceIdentity), "aws:"):
+		return fmt.Errorf("source identity %q must not start with aws:", sourceIdentity)
+	default:
+		return nil
+	}
+}
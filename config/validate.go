@@ -0,0 +1,555 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// knownKeys is the set of keys that are allowed within a profile section,
+// including both standard keys and those specific to aws-auth.
+// https://docs.aws.amazon.com/sdkref/latest/guide/settings-reference.html
+var knownKeys = map[string]bool{
+	// Standard keys.
+	"aws_access_key_id":       true,
+	"aws_account_id":          true,
+	"aws_secret_access_key":   true,
+	"aws_session_token":       true,
+	"ca_bundle":               true,
+	"credential_process":      true,
+	"credential_source":       true,
+	"duration_seconds":        true,
+	"endpoint_url":            true,
+	"external_id":             true,
+	"max_attempts":            true,
+	"mfa_serial":              true,
+	"output":                  true,
+	"parameter_validation":    true,
+	"region":                  true,
+	"retry_mode":              true,
+	"role_arn":                true,
+	"role_session_name":       true,
+	"services":                true,
+	"source_profile":          true,
+	"sts_regional_endpoints":  true,
+	"use_dualstack_endpoint":  true,
+	"use_fips_endpoint":       true,
+	"web_identity_token_file": true,
+
+	// Keys specific to aws-auth.
+	"federate":               true,
+	"inherit":                true,
+	"mfa_message":            true,
+	"name":                   true,
+	"policies":               true,
+	"principal_arn":          true,
+	"saml_assertion_command": true,
+	"saml_assertion_file":    true,
+	"saml_idp_url":           true,
+	"saml_listen_address":    true,
+	"source_identity":        true,
+	"tags":                   true,
+	"transitive_tag_keys":    true,
+	"yubikey_slot":           true,
+}
+
+// knownKeyPrefixes are prefixes for families of standard keys that are
+// allowed within a profile section.
+var knownKeyPrefixes = []string{
+	"cli_",
+	"s3",
+	"sso_",
+}
+
+var (
+	// roleARNPattern matches an IAM role ARN in any partition.
+	roleARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+
+	// mfaSerialPattern matches either a virtual MFA device ARN, or the serial
+	// number of a hardware MFA device.
+	mfaSerialPattern = regexp.MustCompile(`^(arn:aws[a-z-]*:iam::\d{12}:(mfa|u2f)/[\w+=,.@/-]+|[A-Z0-9]{9,})$`)
+
+	// principalARNPattern matches an IAM SAML provider ARN in any partition.
+	principalARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:saml-provider/[\w.-]+$`)
+)
+
+// Duration limits, in seconds, for the various STS API calls.
+// https://docs.aws.amazon.com/STS/latest/APIReference/API_AssumeRole.html
+// https://docs.aws.amazon.com/STS/latest/APIReference/API_GetSessionToken.html
+// https://docs.aws.amazon.com/STS/latest/APIReference/API_GetFederationToken.html
+const (
+	minDurationSeconds        = 900    // 15 minutes
+	maxRoleDurationSeconds    = 43200  // 12 hours
+	maxSessionDurationSeconds = 129600 // 36 hours
+)
+
+// Problem is a single issue found while validating the AWS config files.
+type Problem struct {
+	File    string
+	Line    int
+	Profile string
+	Message string
+}
+
+// String formats the Problem, including as much location information as is
+// known.
+// "/home/user/.aws/config:12: profile dev: unknown key rol_arn"
+func (p Problem) String() string {
+	var prefix string
+	switch {
+	case p.File != "" && p.Line != 0:
+		prefix = fmt.Sprintf("%s:%d: ", p.File, p.Line)
+	case p.File != "":
+		prefix = p.File + ": "
+	}
+
+	if p.Profile != "" {
+		prefix += "profile " + p.Profile + ": "
+	}
+
+	return prefix + p.Message
+}
+
+// Validate finds, loads, and parses the AWS config/credentials files, and
+// checks every profile within them for problems, without making any API
+// calls. The problems found are returned in file and line order.
+func Validate() ([]Problem, error) {
+	cfg := load()
+
+	v := validator{
+		cfg:       cfg,
+		scans:     make(map[string]*scan),
+		templates: make(map[string]struct{}),
+	}
+
+	if err := v.scanFiles(); err != nil {
+		return nil, err
+	}
+
+	// Find profiles that are inherited from, as they are treated as templates.
+	for _, name := range cfg.Profiles() {
+		if section, found := cfg.profile(name); found {
+			if parent := section.Key(inheritKey).Value(); parent != "" {
+				v.templates[parent] = struct{}{}
+			}
+		}
+	}
+
+	for _, name := range cfg.Profiles() {
+		v.validateProfile(name)
+	}
+
+	// Order problems by file, then by line.
+	sort.Slice(v.problems, func(i, j int) bool {
+		a, b := v.problems[i], v.problems[j]
+		switch {
+		case a.File != b.File:
+			return a.File < b.File
+		case a.Line != b.Line:
+			return a.Line < b.Line
+		default:
+			return a.String() < b.String()
+		}
+	})
+
+	return v.problems, nil
+}
+
+// validator accumulates problems while validating a Config.
+type validator struct {
+	cfg       *Config
+	scans     map[string]*scan
+	templates map[string]struct{}
+	problems  []Problem
+}
+
+// report records a problem with the named profile. If a key is given, the
+// location of that key is included.
+func (v *validator) report(profile, key, format string, args ...interface{}) {
+	file, line := v.locate(profile, key)
+	v.problems = append(v.problems, Problem{
+		File:    file,
+		Line:    line,
+		Profile: profile,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// locate finds the file and line that defines the given key in the named
+// profile. If the key isn't found, the location of the section is returned.
+func (v *validator) locate(profile, key string) (string, int) {
+	for _, path := range v.cfg.sources[profile] {
+		scan, found := v.scans[path]
+		if !found {
+			continue
+		}
+
+		for _, section := range []string{"profile " + profile, profile} {
+			if line, found := scan.keys[section][key]; found && key != "" {
+				return path, line
+			}
+			if line, found := scan.sections[section]; found {
+				return path, line
+			}
+		}
+	}
+
+	return "", 0
+}
+
+// scanFiles scans every config/credentials file, reporting syntax errors
+// and unknown keys.
+func (v *validator) scanFiles() error {
+	// Files that failed to parse altogether.
+	for path, err := range v.cfg.failed {
+		scan, scanErr := scanFile(path)
+		if scanErr != nil {
+			return scanErr
+		}
+
+		// Report the lines found to be malformed, falling back to the parse
+		// error itself if none were found.
+		if len(scan.malformed) == 0 {
+			v.problems = append(v.problems, Problem{
+				File:    path,
+				Message: err.Error(),
+			})
+		}
+		for _, line := range scan.malformed {
+			v.problems = append(v.problems, Problem{
+				File:    path,
+				Line:    line,
+				Message: "malformed line, expected a [section] or key = value",
+			})
+		}
+	}
+
+	// Variables can be referenced by any profile, so gather their names
+	// ahead of time, as they are allowed keys.
+	variables := make(map[string]bool)
+	for _, files := range [][]string{v.cfg.configFiles, v.cfg.credentialsFiles} {
+		for _, path := range files {
+			scan, err := scanFile(path)
+			if err != nil {
+				return err
+			}
+			v.scans[path] = scan
+
+			for name := range scan.variables {
+				variables[name] = true
+			}
+		}
+	}
+
+	for _, path := range v.cfg.configFiles {
+		v.checkSections(path, false, variables)
+	}
+	for _, path := range v.cfg.credentialsFiles {
+		v.checkSections(path, true, variables)
+	}
+
+	return nil
+}
+
+// checkSections reports sections that will be ignored, and unknown keys
+// within profile sections, for the given scanned file.
+func (v *validator) checkSections(path string, credentials bool, variables map[string]bool) {
+	scan := v.scans[path]
+	for section, sectionLine := range scan.sections {
+		switch {
+		case section == ini.DefaultSection:
+			// The top of the file may only contain include directives.
+			for key, line := range scan.keys[section] {
+				if key == includeKey {
+					for _, err := range v.cfg.includeErrors[path] {
+						v.problems = append(v.problems, Problem{
+							File:    path,
+							Line:    line,
+							Message: err.Error(),
+						})
+					}
+				} else {
+					v.problems = append(v.problems, Problem{
+						File:    path,
+						Line:    line,
+						Message: fmt.Sprintf("key %s must be inside of a section", key),
+					})
+				}
+			}
+			continue
+
+		case credentials, section == "default", strings.HasPrefix(section, "profile "):
+			// Section is a profile.
+
+		case strings.HasPrefix(section, "services "), strings.HasPrefix(section, "sso-session "):
+			// Section is a supporting section, and is not checked further.
+			continue
+
+		default:
+			v.problems = append(v.problems, Problem{
+				File:    path,
+				Line:    sectionLine,
+				Message: fmt.Sprintf("section [%s] will be ignored, did you mean [profile %s]?", section, section),
+			})
+			continue
+		}
+
+		for key, line := range scan.keys[section] {
+			if !isKnownKey(key) && !variables[key] {
+				v.problems = append(v.problems, Problem{
+					File:    path,
+					Line:    line,
+					Profile: profileName(section),
+					Message: fmt.Sprintf("unknown key %s", key),
+				})
+			}
+		}
+	}
+}
+
+// validateProfile reports any problems with the named profile.
+func (v *validator) validateProfile(name string) {
+	// Templates may reference variables that are only defined by the
+	// profiles that inherit them, so those are not checked any further.
+	if _, found := v.templates[name]; found && v.unresolved(name) {
+		return
+	}
+
+	user, role, session, federate, saml, err := v.cfg.Profile(name)
+	if err != nil {
+		// A role without a source profile is the most likely culprit of an
+		// otherwise invalid profile, so give a more specific message.
+		if section, err := v.cfg.resolve(name); err == nil && section.HasKey("role_arn") && !section.HasKey("source_profile") {
+			v.report(name, "role_arn", "role_arn requires a source_profile")
+			return
+		}
+
+		// Location information is reported separately.
+		var perr profileError
+		if errors.As(err, &perr) {
+			err = perr.err
+		}
+
+		// Report errors for the value of a single key at that key.
+		var kerr keyError
+		if errors.As(err, &kerr) {
+			v.report(name, kerr.key, "%v", err)
+			return
+		}
+		v.report(name, "", "%v", err)
+		return
+	}
+
+	switch {
+	case user != nil:
+		return
+
+	case role != nil:
+		if !roleARNPattern.MatchString(role.RoleARN) {
+			v.report(name, "role_arn", "malformed role_arn %s", role.RoleARN)
+		}
+		if role.MFASerial != "" && !mfaSerialPattern.MatchString(role.MFASerial) {
+			v.report(name, "mfa_serial", "malformed mfa_serial %s", role.MFASerial)
+		}
+		v.checkDuration(name, maxRoleDurationSeconds, true)
+		v.checkSourceProfile(name, role.SourceProfile)
+
+	case session != nil:
+		if session.MFASerial != "" && !mfaSerialPattern.MatchString(session.MFASerial) {
+			v.report(name, "mfa_serial", "malformed mfa_serial %s", session.MFASerial)
+		}
+		v.checkDuration(name, maxSessionDurationSeconds, false)
+		v.checkSourceProfile(name, session.SourceProfile)
+
+	case federate != nil:
+		v.checkDuration(name, maxSessionDurationSeconds, false)
+		v.checkSourceProfile(name, federate.SourceProfile)
+
+	case saml != nil:
+		if saml.RoleARN != "" && !roleARNPattern.MatchString(saml.RoleARN) {
+			v.report(name, "role_arn", "malformed role_arn %s", saml.RoleARN)
+		}
+		if saml.PrincipalARN != "" && !principalARNPattern.MatchString(saml.PrincipalARN) {
+			v.report(name, "principal_arn", "malformed principal_arn %s", saml.PrincipalARN)
+		}
+		v.checkDuration(name, maxRoleDurationSeconds, true)
+	}
+}
+
+// unresolved reports whether any value of the named profile still contains a
+// variable reference after substitution.
+func (v *validator) unresolved(name string) bool {
+	section, err := v.cfg.resolve(name)
+	if err != nil {
+		return false
+	}
+
+	for _, key := range section.Keys() {
+		if variablePattern.MatchString(key.Value()) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDuration reports a duration_seconds value for the named profile that
+// is not a number, or is outside of the allowed range. Profiles that assume a
+// role may also use a value of "max".
+func (v *validator) checkDuration(name string, max int, allowMax bool) {
+	section, err := v.cfg.resolve(name)
+	if err != nil || !section.HasKey("duration_seconds") {
+		return
+	}
+
+	if allowMax && section.Key("duration_seconds").Value() == "max" {
+		return
+	}
+
+	duration, err := section.Key("duration_seconds").Int()
+	switch {
+	case err != nil:
+		v.report(name, "duration_seconds", "malformed duration_seconds %s", section.Key("duration_seconds").Value())
+	case duration < minDurationSeconds || duration > max:
+		v.report(name, "duration_seconds", "duration_seconds %d is outside of the allowed range %d-%d", duration, minDurationSeconds, max)
+	}
+}
+
+// checkSourceProfile reports a source_profile for the named profile that
+// references an unknown profile, or that forms a cycle.
+func (v *validator) checkSourceProfile(name, source string) {
+	path := []string{name}
+	seen := map[string]struct{}{
+		name: {},
+	}
+
+	for current := source; current != ""; current = v.cfg.Summarize(current).SourceProfile {
+		path = append(path, current)
+
+		if _, found := v.cfg.profile(current); !found {
+			if current == source {
+				v.report(name, "source_profile", "source_profile references unknown profile %s", current)
+			}
+			return
+		}
+
+		if _, found := seen[current]; found {
+			v.report(name, "source_profile", "recursive source_profile chain %s", strings.Join(path, " → "))
+			return
+		}
+		seen[current] = struct{}{}
+	}
+}
+
+// isKnownKey returns true if the given key is allowed within a profile.
+func isKnownKey(key string) bool {
+	if knownKeys[key] {
+		return true
+	}
+
+	for _, prefix := range knownKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scan holds the line numbers of sections and keys within a file.
+type scan struct {
+	// sections maps each section name to the line it starts on.
+	sections map[string]int
+
+	// keys maps each section name to the keys in it, and the line they are
+	// defined on.
+	keys map[string]map[string]int
+
+	// variables is the set of variable names referenced in any value.
+	variables map[string]struct{}
+
+	// malformed lists the lines that could not be parsed.
+	malformed []int
+}
+
+// scanFile reads the named file line by line, recording the location of each
+// section and key. This is a loose approximation of the ini parser, used only
+// for providing line numbers.
+func scanFile(path string) (*scan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result := scan{
+		sections: map[string]int{
+			ini.DefaultSection: 0,
+		},
+		keys: map[string]map[string]int{
+			ini.DefaultSection: {},
+		},
+		variables: make(map[string]struct{}),
+	}
+
+	section := ini.DefaultSection
+	nested := false
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, ";"):
+			// Line is blank or a comment.
+
+		case nested && trimmed != text:
+			// Line is an indented nested value, like in a services section.
+
+		case strings.HasPrefix(trimmed, "["):
+			if !strings.HasSuffix(trimmed, "]") {
+				result.malformed = append(result.malformed, line)
+				continue
+			}
+
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if _, found := result.sections[section]; !found {
+				result.sections[section] = line
+				result.keys[section] = make(map[string]int)
+			}
+			nested = false
+
+		default:
+			index := strings.IndexAny(trimmed, "=:")
+			if index < 1 {
+				result.malformed = append(result.malformed, line)
+				continue
+			}
+
+			key := strings.TrimSpace(trimmed[:index])
+			value := strings.TrimSpace(trimmed[index+1:])
+			result.keys[section][key] = line
+			nested = value == ""
+
+			for _, match := range variablePattern.FindAllStringSubmatch(value, -1) {
+				result.variables[match[1]] = struct{}{}
+			}
+		}
+	}
+
+	// Drop the implicit "[DEFAULT]" section if nothing was in it.
+	if len(result.keys[ini.DefaultSection]) == 0 {
+		delete(result.sections, ini.DefaultSection)
+	}
+
+	return &result, scanner.Err()
+}
@@ -1,21 +1,39 @@
 package console
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/httpclient"
 )
 
+// defaultSigninURL is the AWS Console federation endpoint used when one is not
+// otherwise configured.
+const defaultSigninURL = "https://signin.aws.amazon.com"
+
 // GenerateLoginURL takes the given sts.Credentials and generates a url.URL
-// that can be used to login to the AWS Console.
+// that can be used to login to the AWS Console. The request is made against
+// the federation endpoint described by the given config.Endpoint, and is
+// abandoned if the given context.Context is canceled.
 // https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_providers_enable-console-custom-url.html
-func GenerateLoginURL(creds *sts.Credentials) (*url.URL, error) {
-	federationURL, _ := url.Parse("https://signin.aws.amazon.com/federation")
+func GenerateLoginURL(ctx context.Context, creds *sts.Credentials, endpoint config.Endpoint) (*url.URL, error) {
+	base := defaultSigninURL
+	if endpoint.SigninURL != "" {
+		base = strings.TrimSuffix(endpoint.SigninURL, "/")
+	}
+
+	federationURL, err := url.Parse(base + "/federation")
+	if err != nil {
+		return nil, err
+	}
 
 	type requestCredentials struct {
 		SessionID    string `json:"sessionId"`
@@ -43,7 +61,17 @@ func GenerateLoginURL(creds *sts.Credentials) (*url.URL, error) {
 	federationURL.RawQuery = values.Encode()
 
 	// Perform the actual API request.
-	resp, err := http.Get(federationURL.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, federationURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := httpclient.FromContext(ctx).For(endpoint.CABundle)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +89,7 @@ func GenerateLoginURL(creds *sts.Credentials) (*url.URL, error) {
 	}
 
 	// Format sign-in URL and return it!
-	return signinURL(token), nil
+	return signinURL(federationURL, token), nil
 }
 
 // extractToken parses the response JSON from a getSigninToken request and
@@ -79,9 +107,10 @@ func extractToken(reader io.Reader) (string, error) {
 	return resp.SigninToken, nil
 }
 
-// signinURL formats a AWS console login url using the given sign-in token.
-func signinURL(token string) *url.URL {
-	result, _ := url.Parse("https://signin.aws.amazon.com/federation")
+// signinURL formats a AWS console login url using the given federation
+// endpoint and sign-in token.
+func signinURL(federationURL *url.URL, token string) *url.URL {
+	result := *federationURL
 
 	values := url.Values{
 		"Action":      []string{"login"},
@@ -90,5 +119,5 @@ func signinURL(token string) *url.URL {
 	}
 	result.RawQuery = values.Encode()
 
-	return result
+	return &result
 }
@@ -0,0 +1,103 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package console
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/fakests"
+)
+
+func TestGenerateLoginURL(t *testing.T) {
+	server := httptest.NewServer(fakests.New(fakests.Config{
+		Users: []fakests.User{
+			{
+				ARN:             "arn:aws:iam::111111111111:user/alice",
+				AccessKeyID:     "AKIAALICE",
+				SecretAccessKey: "secret",
+			},
+		},
+	}))
+	defer server.Close()
+
+	endpoint := config.Endpoint{
+		SigninURL: server.URL,
+	}
+
+	// Obtain temporary credentials, as long-lived ones can't be used to
+	// sign in.
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKIAALICE", "secret", ""),
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	output, err := sts.New(sess).GetFederationToken(&sts.GetFederationTokenInput{
+		Name: aws.String("alice"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	tests := []struct {
+		creds    *sts.Credentials
+		expected string
+	}{
+		{
+			creds:    output.Credentials,
+			expected: "Signed in to the AWS Console as arn:aws:sts::111111111111:federated-user/alice",
+		},
+		{
+			creds: &sts.Credentials{
+				AccessKeyId:     aws.String("AKIAALICE"),
+				SecretAccessKey: aws.String("secret"),
+			},
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			loginURL, err := GenerateLoginURL(context.Background(), test.creds, endpoint)
+			switch {
+			case err != nil && test.expected == "":
+				return
+			case err != nil:
+				t.Fatalf("expected no error but got error %q", err)
+			case test.expected == "":
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if !strings.HasPrefix(loginURL.String(), server.URL+"/federation?") {
+				t.Fatalf("expected url for %s but got %s", server.URL, loginURL)
+			}
+
+			// Follow the login url, to verify that the sign-in token works.
+			resp, err := http.Get(loginURL.String())
+			if err != nil {
+				t.Fatalf("expected no error but got error %q", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := ioutil.ReadAll(resp.Body)
+			if actual := strings.TrimSpace(string(body)); actual != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package fakests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// maxClockSkew is how far the time that a request was signed at may be from
+// the current time.
+const maxClockSkew = 15 * time.Minute
+
+// authorizationPattern matches the Authorization header of a request signed
+// with AWS Signature Version 4.
+var authorizationPattern = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/[^/]+/([^/]+)/([^/]+)/aws4_request, ?SignedHeaders=([^,]+), ?Signature=([0-9a-f]+)$`)
+
+// authenticate verifies the signature of the given request, and returns the
+// principal that signed it. The request form is parsed as a side effect.
+func (s *Server) authenticate(r *http.Request) (*principal, *stsError) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, &stsError{http.StatusBadRequest, "InvalidRequest", err.Error()}
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := r.ParseForm(); err != nil {
+		return nil, &stsError{http.StatusBadRequest, "InvalidRequest", err.Error()}
+	}
+
+	match := authorizationPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil {
+		return nil, &stsError{http.StatusForbidden, "MissingAuthenticationToken", "Request is missing Authentication Token"}
+	}
+	accessKeyID, region, service, signedHeaders, signature := match[1], match[2], match[3], match[4], match[5]
+
+	// The access key id must be known, and any session token must match.
+	caller, found := s.lookup(accessKeyID)
+	if !found || caller.SessionToken != r.Header.Get("X-Amz-Security-Token") {
+		return nil, &stsError{http.StatusForbidden, "InvalidClientTokenId", "The security token included in the request is invalid."}
+	}
+
+	// Requests signed too long ago, or too far in the future, are rejected.
+	now := time.Now()
+	signTime, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, &stsError{http.StatusForbidden, "IncompleteSignature", "Date must be in ISO-8601 'basic format'."}
+	}
+	if signTime.Before(now.Add(-maxClockSkew)) || signTime.After(now.Add(maxClockSkew)) {
+		return nil, &stsError{http.StatusForbidden, "SignatureDoesNotMatch", "Signature expired: " + r.Header.Get("X-Amz-Date") + " is now earlier than " + now.Add(-maxClockSkew).UTC().Format("20060102T150405Z")}
+	}
+
+	// Sign a copy of the request with the caller's secret key, and compare
+	// signatures.
+	signed, _ := http.NewRequest(r.Method, (&url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}).String(), nil)
+	for _, name := range strings.Split(signedHeaders, ";") {
+		if name != "host" {
+			signed.Header[http.CanonicalHeaderKey(name)] = r.Header.Values(name)
+		}
+	}
+
+	signer := v4.NewSigner(credentials.NewStaticCredentials(accessKeyID, caller.SecretAccessKey, caller.SessionToken))
+	if _, err := signer.Sign(signed, bytes.NewReader(body), service, region, signTime); err != nil {
+		return nil, &stsError{http.StatusForbidden, "SignatureDoesNotMatch", err.Error()}
+	}
+	if !strings.HasSuffix(signed.Header.Get("Authorization"), "Signature="+signature) {
+		return nil, &stsError{http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided. Check your AWS Secret Access Key and signing method. Consult the service documentation for details."}
+	}
+
+	// Issued credentials expire.
+	if caller.Temporary && now.After(caller.Expiration) {
+		return nil, &stsError{http.StatusForbidden, "ExpiredToken", "The security token included in the request is expired"}
+	}
+
+	return caller, nil
+}
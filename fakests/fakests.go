@@ -0,0 +1,202 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+// Package fakests provides a local fake of the AWS STS API and the AWS
+// Console federation endpoint, for testing profiles without AWS. Point a
+// profile's endpoint_url (or a services section) at it, along with the
+// access keys of a configured user.
+package fakests
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config describes the users and roles known to a Server.
+type Config struct {
+	Users []User `json:"users"`
+	Roles []Role `json:"roles"`
+}
+
+// User is an IAM user with long-lived access keys.
+type User struct {
+	// ARN is the user's ARN, like "arn:aws:iam::000000000000:user/alice".
+	ARN string `json:"arn"`
+
+	// AccessKeyID and SecretAccessKey are the user's access keys.
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+
+	// MFASerial is the user's MFA device, if any, and MFACode is the only
+	// code that it accepts.
+	MFASerial string `json:"mfa_serial,omitempty"`
+	MFACode   string `json:"mfa_code,omitempty"`
+}
+
+// Role is an IAM role that can be assumed.
+type Role struct {
+	// ARN is the role's ARN, like "arn:aws:iam::000000000000:role/admin".
+	ARN string `json:"arn"`
+
+	// Trust lists the principals allowed to assume the role. Each is the
+	// ARN of a user or a role, the root ARN of an account to trust every
+	// principal in it, like "arn:aws:iam::000000000000:root", or "*" to
+	// trust everyone.
+	Trust []string `json:"trust"`
+
+	// ExternalID is the external id that callers must give, if any.
+	ExternalID string `json:"external_id,omitempty"`
+
+	// RequireMFA requires callers to have authenticated with MFA.
+	RequireMFA bool `json:"require_mfa,omitempty"`
+
+	// MaxSessionDuration is the longest session allowed, in seconds. It
+	// defaults to 1 hour.
+	MaxSessionDuration int `json:"max_session_duration,omitempty"`
+}
+
+// LoadConfig reads a JSON Config from the named file.
+func LoadConfig(filename string) (Config, error) {
+	body, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(body, &cfg); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// principal is whoever a set of access keys belongs to.
+type principal struct {
+	// ARN is the principal's identity, as returned by GetCallerIdentity.
+	ARN string
+
+	// Source is what trust policies match against, which is the role ARN
+	// for assumed roles, and the ARN for anyone else.
+	Source string
+
+	// UserID is the principal's unique id, as returned by
+	// GetCallerIdentity.
+	UserID string
+
+	SecretAccessKey string
+	SessionToken    string
+
+	// Temporary is set for credentials issued by the Server, which expire.
+	Temporary  bool
+	Expiration time.Time
+
+	// MFA is set if the credentials were issued with an MFA code.
+	MFA bool
+
+	// User is the user that the credentials belong to, if any.
+	User *User
+}
+
+// account returns the principal's AWS account id.
+func (p principal) account() string {
+	return accountOf(p.ARN)
+}
+
+// accountOf returns the AWS account id from the given ARN.
+func accountOf(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 5 {
+		return ""
+	}
+	return parts[4]
+}
+
+// Server is an http.Handler faking the AWS STS API, and the AWS Console
+// federation endpoint at /federation.
+type Server struct {
+	config Config
+
+	lock       sync.Mutex
+	principals map[string]*principal
+	signins    map[string]*principal
+}
+
+// New creates a Server with the users and roles from the given Config.
+func New(cfg Config) *Server {
+	server := &Server{
+		config:     cfg,
+		principals: make(map[string]*principal),
+		signins:    make(map[string]*principal),
+	}
+
+	for index := range cfg.Users {
+		user := &server.config.Users[index]
+		server.principals[user.AccessKeyID] = &principal{
+			ARN:             user.ARN,
+			Source:          user.ARN,
+			UserID:          "AIDA" + strings.ToUpper(randomString(16)),
+			SecretAccessKey: user.SecretAccessKey,
+			User:            user,
+		}
+	}
+
+	return server
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/federation" {
+		s.serveFederation(w, r)
+		return
+	}
+
+	s.serveSTS(w, r)
+}
+
+// issue creates new temporary credentials for the given principal, and
+// returns their access key id.
+func (s *Server) issue(p principal, duration time.Duration) (string, *principal) {
+	accessKeyID := "ASIA" + strings.ToUpper(randomString(16))
+	p.SecretAccessKey = randomString(40)
+	p.SessionToken = randomString(64)
+	p.Temporary = true
+	p.Expiration = time.Now().Add(duration).UTC().Truncate(time.Second)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.principals[accessKeyID] = &p
+	return accessKeyID, &p
+}
+
+// lookup returns the principal that the given access key id belongs to.
+func (s *Server) lookup(accessKeyID string) (*principal, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	p, found := s.principals[accessKeyID]
+	return p, found
+}
+
+// role returns the configured role with the given ARN.
+func (s *Server) role(arn string) (*Role, bool) {
+	for index := range s.config.Roles {
+		if s.config.Roles[index].ARN == arn {
+			return &s.config.Roles[index], true
+		}
+	}
+	return nil, false
+}
+
+// randomString returns a random hex string of the given length.
+func randomString(length int) string {
+	buf := make([]byte, (length+1)/2)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)[:length]
+}
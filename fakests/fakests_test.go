@@ -0,0 +1,173 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package fakests
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func TestServer(t *testing.T) {
+	server := New(Config{
+		Users: []User{
+			{
+				ARN:             "arn:aws:iam::111111111111:user/alice",
+				AccessKeyID:     "AKIAALICE",
+				SecretAccessKey: "secret",
+				MFASerial:       "arn:aws:iam::111111111111:mfa/alice",
+				MFACode:         "123456",
+			},
+		},
+		Roles: []Role{
+			{
+				ARN:                "arn:aws:iam::222222222222:role/admin",
+				Trust:              []string{"arn:aws:iam::111111111111:user/alice"},
+				RequireMFA:         true,
+				MaxSessionDuration: 7200,
+			},
+			{
+				ARN:   "arn:aws:iam::222222222222:role/readonly",
+				Trust: []string{"arn:aws:iam::222222222222:role/admin"},
+			},
+		},
+	})
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	// client returns an STS client for the given credentials.
+	client := func(creds *sts.Credentials) *sts.STS {
+		sess := session.Must(session.NewSession(&aws.Config{
+			Credentials: credentials.NewStaticCredentials(aws.StringValue(creds.AccessKeyId), aws.StringValue(creds.SecretAccessKey), aws.StringValue(creds.SessionToken)),
+			Endpoint:    aws.String(httpServer.URL),
+			Region:      aws.String("us-east-1"),
+			MaxRetries:  aws.Int(0),
+		}))
+		return sts.New(sess)
+	}
+
+	alice := &sts.Credentials{
+		AccessKeyId:     aws.String("AKIAALICE"),
+		SecretAccessKey: aws.String("secret"),
+	}
+
+	// Obtain MFA authenticated session credentials, which the admin role
+	// requires.
+	mfaSession, err := client(alice).GetSessionToken(&sts.GetSessionTokenInput{
+		SerialNumber: aws.String("arn:aws:iam::111111111111:mfa/alice"),
+		TokenCode:    aws.String("123456"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	admin, err := client(mfaSession.Credentials).AssumeRole(&sts.AssumeRoleInput{
+		DurationSeconds: aws.Int64(7200),
+		RoleArn:         aws.String("arn:aws:iam::222222222222:role/admin"),
+		RoleSessionName: aws.String("alice"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	tests := []struct {
+		creds *sts.Credentials
+		input *sts.AssumeRoleInput
+		code  string
+	}{
+		{
+			creds: alice,
+			input: &sts.AssumeRoleInput{
+				RoleArn:         aws.String("arn:aws:iam::222222222222:role/admin"),
+				RoleSessionName: aws.String("alice"),
+			},
+			code: "AccessDenied",
+		},
+		{
+			creds: alice,
+			input: &sts.AssumeRoleInput{
+				RoleArn:         aws.String("arn:aws:iam::222222222222:role/admin"),
+				RoleSessionName: aws.String("alice"),
+				SerialNumber:    aws.String("arn:aws:iam::111111111111:mfa/alice"),
+				TokenCode:       aws.String("000000"),
+			},
+			code: "AccessDenied",
+		},
+		{
+			creds: alice,
+			input: &sts.AssumeRoleInput{
+				RoleArn:         aws.String("arn:aws:iam::222222222222:role/admin"),
+				RoleSessionName: aws.String("alice"),
+				SerialNumber:    aws.String("arn:aws:iam::111111111111:mfa/alice"),
+				TokenCode:       aws.String("123456"),
+			},
+		},
+		{
+			creds: mfaSession.Credentials,
+			input: &sts.AssumeRoleInput{
+				DurationSeconds: aws.Int64(10800),
+				RoleArn:         aws.String("arn:aws:iam::222222222222:role/admin"),
+				RoleSessionName: aws.String("alice"),
+			},
+			code: "ValidationError",
+		},
+		{
+			creds: admin.Credentials,
+			input: &sts.AssumeRoleInput{
+				RoleArn:         aws.String("arn:aws:iam::222222222222:role/readonly"),
+				RoleSessionName: aws.String("alice"),
+			},
+		},
+		{
+			creds: admin.Credentials,
+			input: &sts.AssumeRoleInput{
+				DurationSeconds: aws.Int64(7200),
+				RoleArn:         aws.String("arn:aws:iam::222222222222:role/readonly"),
+				RoleSessionName: aws.String("alice"),
+			},
+			code: "ValidationError",
+		},
+		{
+			creds: mfaSession.Credentials,
+			input: &sts.AssumeRoleInput{
+				RoleArn:         aws.String("arn:aws:iam::222222222222:role/readonly"),
+				RoleSessionName: aws.String("alice"),
+			},
+			code: "AccessDenied",
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			_, err := client(test.creds).AssumeRole(test.input)
+			switch {
+			case err != nil && test.code == "":
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.code != "":
+				t.Fatalf("expected error %q but got no error", test.code)
+			case err != nil:
+				if code := err.(awserr.Error).Code(); code != test.code {
+					t.Fatalf("expected error %q but got %q", test.code, code)
+				}
+			}
+		})
+	}
+
+	// Issued credentials stop working once they expire.
+	issued, _ := server.lookup(aws.StringValue(admin.Credentials.AccessKeyId))
+	issued.Expiration = time.Now().Add(-time.Minute)
+
+	_, err = client(admin.Credentials).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err == nil || err.(awserr.Error).Code() != "ExpiredToken" {
+		t.Fatalf("expected error %q but got %v", "ExpiredToken", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package fakests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// serveFederation fakes the AWS Console federation endpoint. The
+// getSigninToken action exchanges temporary credentials for a sign-in token,
+// and the login action shows who a sign-in token belongs to.
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_providers_enable-console-custom-url.html
+func (s *Server) serveFederation(w http.ResponseWriter, r *http.Request) {
+	switch r.FormValue("Action") {
+	case "getSigninToken":
+		var session struct {
+			SessionID    string `json:"sessionId"`
+			SessionKey   string `json:"sessionKey"`
+			SessionToken string `json:"sessionToken"`
+		}
+		if err := json.Unmarshal([]byte(r.FormValue("Session")), &session); err != nil {
+			http.Error(w, "invalid Session", http.StatusBadRequest)
+			return
+		}
+
+		// Only unexpired temporary credentials can be exchanged.
+		caller, found := s.lookup(session.SessionID)
+		if !found || !caller.Temporary || caller.SecretAccessKey != session.SessionKey || caller.SessionToken != session.SessionToken || time.Now().After(caller.Expiration) {
+			http.Error(w, "invalid credentials", http.StatusBadRequest)
+			return
+		}
+
+		token := randomString(64)
+		s.lock.Lock()
+		s.signins[token] = caller
+		s.lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"SigninToken": token,
+		})
+
+	case "login":
+		s.lock.Lock()
+		caller, found := s.signins[r.FormValue("SigninToken")]
+		s.lock.Unlock()
+
+		if !found {
+			http.Error(w, "invalid SigninToken", http.StatusBadRequest)
+			return
+		}
+
+		fmt.Fprintf(w, "Signed in to the AWS Console as %s\n", caller.ARN)
+
+	default:
+		http.Error(w, "unknown Action", http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package fakests
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stsNamespace is the XML namespace of every STS response.
+const stsNamespace = "https://sts.amazonaws.com/doc/2011-06-15/"
+
+// Limits on durations, matching those enforced by AWS.
+const (
+	minDuration            = 15 * time.Minute
+	defaultRoleDuration    = time.Hour
+	maxChainedRoleDuration = time.Hour
+	defaultSessionDuration = 12 * time.Hour
+	maxSessionDuration     = 36 * time.Hour
+)
+
+// sessionNamePattern matches valid role session names.
+var sessionNamePattern = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+
+// stsError is an STS error response.
+type stsError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *stsError) Error() string {
+	return e.code + ": " + e.message
+}
+
+// accessDenied returns an AccessDenied error for the given message.
+func accessDenied(format string, args ...interface{}) *stsError {
+	return &stsError{http.StatusForbidden, "AccessDenied", fmt.Sprintf(format, args...)}
+}
+
+// validationError returns a ValidationError for the given message.
+func validationError(format string, args ...interface{}) *stsError {
+	return &stsError{http.StatusBadRequest, "ValidationError", fmt.Sprintf(format, args...)}
+}
+
+// credentialsResult is the credentials element of several responses.
+type credentialsResult struct {
+	AccessKeyID     string `xml:"AccessKeyId"`
+	SecretAccessKey string `xml:"SecretAccessKey"`
+	SessionToken    string `xml:"SessionToken"`
+	Expiration      string `xml:"Expiration"`
+}
+
+// newCredentialsResult returns the credentials element for the given issued
+// credentials.
+func newCredentialsResult(accessKeyID string, p *principal) credentialsResult {
+	return credentialsResult{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: p.SecretAccessKey,
+		SessionToken:    p.SessionToken,
+		Expiration:      p.Expiration.Format(time.RFC3339),
+	}
+}
+
+type assumeRoleResult struct {
+	XMLName        xml.Name          `xml:"AssumeRoleResult"`
+	Credentials    credentialsResult `xml:"Credentials"`
+	AssumedRoleARN string            `xml:"AssumedRoleUser>Arn"`
+	AssumedRoleID  string            `xml:"AssumedRoleUser>AssumedRoleId"`
+	SourceIdentity string            `xml:"SourceIdentity,omitempty"`
+}
+
+type sessionTokenResult struct {
+	XMLName     xml.Name          `xml:"GetSessionTokenResult"`
+	Credentials credentialsResult `xml:"Credentials"`
+}
+
+type federationTokenResult struct {
+	XMLName          xml.Name          `xml:"GetFederationTokenResult"`
+	Credentials      credentialsResult `xml:"Credentials"`
+	FederatedUserARN string            `xml:"FederatedUser>Arn"`
+	FederatedUserID  string            `xml:"FederatedUser>FederatedUserId"`
+}
+
+type callerIdentityResult struct {
+	XMLName xml.Name `xml:"GetCallerIdentityResult"`
+	ARN     string   `xml:"Arn"`
+	UserID  string   `xml:"UserId"`
+	Account string   `xml:"Account"`
+}
+
+// serveSTS handles a single STS API call.
+func (s *Server) serveSTS(w http.ResponseWriter, r *http.Request) {
+	action, result, err := s.call(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	type response struct {
+		XMLName   xml.Name
+		Namespace string `xml:"xmlns,attr"`
+		Result    interface{}
+		RequestID string `xml:"ResponseMetadata>RequestId"`
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	xml.NewEncoder(w).Encode(response{
+		XMLName:   xml.Name{Local: action + "Response"},
+		Namespace: stsNamespace,
+		Result:    result,
+		RequestID: randomString(32),
+	})
+}
+
+// call authenticates the given request, and makes the STS API call that it
+// describes. The name of the call and its result are returned.
+func (s *Server) call(r *http.Request) (string, interface{}, *stsError) {
+	caller, err := s.authenticate(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch action := r.Form.Get("Action"); action {
+	case "AssumeRole":
+		result, err := s.assumeRole(caller, r)
+		return action, result, err
+	case "GetSessionToken":
+		result, err := s.getSessionToken(caller, r)
+		return action, result, err
+	case "GetFederationToken":
+		result, err := s.getFederationToken(caller, r)
+		return action, result, err
+	case "GetCallerIdentity":
+		return action, callerIdentityResult{
+			ARN:     caller.ARN,
+			UserID:  caller.UserID,
+			Account: caller.account(),
+		}, nil
+	default:
+		return "", nil, &stsError{http.StatusBadRequest, "InvalidAction", fmt.Sprintf("Could not find operation %s for version 2011-06-15", action)}
+	}
+}
+
+// writeError writes the given error as an STS error response.
+func writeError(w http.ResponseWriter, err *stsError) {
+	type response struct {
+		XMLName   xml.Name `xml:"ErrorResponse"`
+		Namespace string   `xml:"xmlns,attr"`
+		Type      string   `xml:"Error>Type"`
+		Code      string   `xml:"Error>Code"`
+		Message   string   `xml:"Error>Message"`
+		RequestID string   `xml:"RequestId"`
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(err.status)
+	xml.NewEncoder(w).Encode(response{
+		Namespace: stsNamespace,
+		Type:      "Sender",
+		Code:      err.code,
+		Message:   err.message,
+		RequestID: randomString(32),
+	})
+}
+
+// assumeRole fakes sts:AssumeRole.
+func (s *Server) assumeRole(caller *principal, r *http.Request) (interface{}, *stsError) {
+	roleARN := r.Form.Get("RoleArn")
+	role, found := s.role(roleARN)
+	if !found || !trusts(role, caller) {
+		return nil, accessDenied("User: %s is not authorized to perform: sts:AssumeRole on resource: %s", caller.ARN, roleARN)
+	}
+
+	sessionName := r.Form.Get("RoleSessionName")
+	if !sessionNamePattern.MatchString(sessionName) {
+		return nil, validationError("1 validation error detected: Value '%s' at 'roleSessionName' failed to satisfy constraint: Member must satisfy regular expression pattern: [\\w+=,.@-]*", sessionName)
+	}
+
+	if r.Form.Get("ExternalId") != role.ExternalID {
+		return nil, accessDenied("User: %s is not authorized to perform: sts:AssumeRole on resource: %s", caller.ARN, roleARN)
+	}
+
+	mfa, err := checkMFA(caller, r)
+	if err != nil {
+		return nil, err
+	}
+	if role.RequireMFA && !mfa {
+		return nil, accessDenied("User: %s is not authorized to perform: sts:AssumeRole on resource: %s", caller.ARN, roleARN)
+	}
+
+	// Roles assumed from another role are limited to 1 hour, regardless of
+	// the role's own limit.
+	maxDuration := time.Duration(role.MaxSessionDuration) * time.Second
+	if maxDuration == 0 {
+		maxDuration = defaultRoleDuration
+	}
+	chained := caller.Source != caller.ARN
+	duration, err := parseDuration(r, defaultRoleDuration)
+	switch {
+	case err != nil:
+		return nil, err
+	case chained && duration > maxChainedRoleDuration:
+		return nil, validationError("The requested DurationSeconds exceeds the 1 hour session limit for roles assumed by role chaining.")
+	case duration > maxDuration:
+		return nil, validationError("The requested DurationSeconds exceeds the MaxSessionDuration set for this role.")
+	}
+
+	roleName := roleARN[strings.LastIndex(roleARN, "/")+1:]
+	roleID := "AROA" + strings.ToUpper(randomString(16))
+
+	accessKeyID, issued := s.issue(principal{
+		ARN:    fmt.Sprintf("arn:aws:sts::%s:assumed-role/%s/%s", accountOf(roleARN), roleName, sessionName),
+		Source: roleARN,
+		UserID: roleID + ":" + sessionName,
+		MFA:    mfa,
+		User:   caller.User,
+	}, duration)
+
+	return assumeRoleResult{
+		Credentials:    newCredentialsResult(accessKeyID, issued),
+		AssumedRoleARN: issued.ARN,
+		AssumedRoleID:  issued.UserID,
+		SourceIdentity: r.Form.Get("SourceIdentity"),
+	}, nil
+}
+
+// getSessionToken fakes sts:GetSessionToken.
+func (s *Server) getSessionToken(caller *principal, r *http.Request) (interface{}, *stsError) {
+	if caller.Temporary {
+		return nil, accessDenied("Cannot call GetSessionToken with session credentials")
+	}
+
+	mfa, err := checkMFA(caller, r)
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := parseDuration(r, defaultSessionDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	accessKeyID, issued := s.issue(principal{
+		ARN:    caller.ARN,
+		Source: caller.Source,
+		UserID: caller.UserID,
+		MFA:    mfa,
+		User:   caller.User,
+	}, duration)
+
+	return sessionTokenResult{
+		Credentials: newCredentialsResult(accessKeyID, issued),
+	}, nil
+}
+
+// getFederationToken fakes sts:GetFederationToken.
+func (s *Server) getFederationToken(caller *principal, r *http.Request) (interface{}, *stsError) {
+	if caller.Temporary {
+		return nil, accessDenied("Cannot call GetFederationToken with session credentials")
+	}
+
+	name := r.Form.Get("Name")
+	if len(name) < 2 || len(name) > 32 || !sessionNamePattern.MatchString(name) {
+		return nil, validationError("1 validation error detected: Value '%s' at 'name' failed to satisfy constraint: Member must satisfy regular expression pattern: [\\w+=,.@-]*", name)
+	}
+
+	duration, err := parseDuration(r, defaultSessionDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	arn := fmt.Sprintf("arn:aws:sts::%s:federated-user/%s", caller.account(), name)
+	accessKeyID, issued := s.issue(principal{
+		ARN:    arn,
+		Source: arn,
+		UserID: caller.account() + ":" + name,
+		User:   caller.User,
+	}, duration)
+
+	return federationTokenResult{
+		Credentials:      newCredentialsResult(accessKeyID, issued),
+		FederatedUserARN: issued.ARN,
+		FederatedUserID:  issued.UserID,
+	}, nil
+}
+
+// trusts reports whether the given role's trust policy allows the given
+// principal to assume it.
+func trusts(role *Role, caller *principal) bool {
+	for _, trusted := range role.Trust {
+		switch trusted {
+		case "*", caller.Source, "arn:aws:iam::" + caller.account() + ":root":
+			return true
+		}
+	}
+	return false
+}
+
+// checkMFA verifies the MFA device and code given with the request, if any,
+// which must belong to the user that the caller's credentials came from.
+// Whether the resulting credentials are MFA authenticated is returned.
+func checkMFA(caller *principal, r *http.Request) (bool, *stsError) {
+	serial := r.Form.Get("SerialNumber")
+	if serial == "" {
+		return caller.MFA, nil
+	}
+
+	if caller.User == nil || caller.User.MFASerial != serial || caller.User.MFACode != r.Form.Get("TokenCode") {
+		return false, accessDenied("MultiFactorAuthentication failed with invalid MFA one time pass code.")
+	}
+
+	return true, nil
+}
+
+// parseDuration returns the DurationSeconds given with the request, or the
+// given default if there is none.
+func parseDuration(r *http.Request, defaultDuration time.Duration) (time.Duration, *stsError) {
+	value := r.Form.Get("DurationSeconds")
+	if value == "" {
+		return defaultDuration, nil
+	}
+
+	seconds, err := strconv.Atoi(value)
+	duration := time.Duration(seconds) * time.Second
+	switch {
+	case err != nil:
+		return 0, validationError("Value '%s' for DurationSeconds is not a number", value)
+	case duration < minDuration:
+		return 0, validationError("1 validation error detected: Value '%d' at 'durationSeconds' failed to satisfy constraint: Member must have value greater than or equal to 900", seconds)
+	case duration > maxSessionDuration:
+		return 0, validationError("1 validation error detected: Value '%d' at 'durationSeconds' failed to satisfy constraint: Member must have value less than or equal to 129600", seconds)
+	}
+
+	return duration, nil
+}
@@ -3,7 +3,8 @@ module github.com/joshdk/aws-auth
 go 1.15
 
 require (
-	github.com/aws/aws-sdk-go v1.35.35
+	github.com/aws/aws-sdk-go v1.44.100
+	github.com/aws/aws-sdk-go-v2 v1.17.8
 	github.com/joshdk/ykmango v0.0.0-20180821154826-65f49fb7dada
 	github.com/pkg/browser v0.0.0-20201112035734-206646e67786
 	github.com/spf13/cobra v1.1.1
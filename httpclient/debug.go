@@ -0,0 +1,99 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"sync"
+)
+
+// redacted replaces any secrets within a request or response dump.
+const redacted = "REDACTED"
+
+// redactions match secrets within request and response dumps, and replace
+// them while preserving the surrounding name.
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// Headers, like "Authorization: AWS4-HMAC-SHA256 ...".
+	{
+		pattern:     regexp.MustCompile(`(?mi)^(Authorization|X-Amz-Security-Token):[^\r\n]*`),
+		replacement: "${1}: " + redacted,
+	},
+	// Form and query parameters, like "TokenCode=123456".
+	{
+		pattern:     regexp.MustCompile(`\b(TokenCode|SAMLAssertion|Session|SessionToken|SecretAccessKey|SigninToken)=[^&\s]*`),
+		replacement: "${1}=" + redacted,
+	},
+	// XML elements, like "<SecretAccessKey>...</SecretAccessKey>".
+	{
+		pattern:     regexp.MustCompile(`<(SecretAccessKey|SessionToken)>[^<]*</`),
+		replacement: "<${1}>" + redacted + "</",
+	},
+	// JSON fields, like `"SigninToken": "..."`.
+	{
+		pattern:     regexp.MustCompile(`"(SigninToken|sessionKey|sessionToken)"(\s*):(\s*)"[^"]*"`),
+		replacement: `"${1}"${2}:${3}"` + redacted + `"`,
+	},
+}
+
+// Redact replaces any secrets, like secret access keys, session tokens, MFA
+// codes, and signatures, within the given request or response dump.
+func Redact(dump []byte) []byte {
+	for _, redaction := range redactions {
+		dump = redaction.pattern.ReplaceAll(dump, []byte(redaction.replacement))
+	}
+	return dump
+}
+
+// debugTransport is an http.RoundTripper that dumps every request and
+// response, with any secrets redacted.
+type debugTransport struct {
+	next http.RoundTripper
+	out  io.Writer
+	lock *sync.Mutex
+}
+
+// Debug wraps the given http.RoundTripper, such that every request and
+// response is dumped to the given writer, with any secrets redacted.
+func Debug(next http.RoundTripper, out io.Writer) http.RoundTripper {
+	return debugTransport{
+		next: next,
+		out:  out,
+		lock: &sync.Mutex{},
+	}
+}
+
+func (d debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if dump, err := httputil.DumpRequestOut(req, true); err == nil {
+		d.write("request", dump)
+	}
+
+	resp, err := d.next.RoundTrip(req)
+	if err != nil {
+		d.write("error", []byte(err.Error()))
+		return nil, err
+	}
+
+	if dump, err := httputil.DumpResponse(resp, true); err == nil {
+		d.write("response", dump)
+	}
+
+	return resp, nil
+}
+
+// write writes a single labeled dump, while holding a lock so that dumps of
+// concurrent requests don't mix.
+func (d debugTransport) write(label string, dump []byte) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	fmt.Fprintf(d.out, "aws-auth: http %s:\n%s\n\n", label, Redact(dump))
+}
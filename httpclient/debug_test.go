@@ -0,0 +1,59 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package httpclient
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		dump     string
+		expected string
+	}{
+		{
+			dump:     "Authorization: AWS4-HMAC-SHA256 Credential=AKIA/20200101/us-east-1/sts/aws4_request, Signature=abc\r\n",
+			expected: "Authorization: REDACTED\r\n",
+		},
+		{
+			dump:     "X-Amz-Security-Token: token\r\nX-Amz-Date: 20200101T000000Z\r\n",
+			expected: "X-Amz-Security-Token: REDACTED\r\nX-Amz-Date: 20200101T000000Z\r\n",
+		},
+		{
+			dump:     "Action=GetSessionToken&SerialNumber=arn%3Aaws%3Aiam%3A%3A000000000000%3Amfa%2Falice&TokenCode=123456&Version=2011-06-15",
+			expected: "Action=GetSessionToken&SerialNumber=arn%3Aaws%3Aiam%3A%3A000000000000%3Amfa%2Falice&TokenCode=REDACTED&Version=2011-06-15",
+		},
+		{
+			dump:     "Action=AssumeRoleWithSAML&SAMLAssertion=PHNhbWw%2B",
+			expected: "Action=AssumeRoleWithSAML&SAMLAssertion=REDACTED",
+		},
+		{
+			dump:     "GET /federation?Action=login&SigninToken=token&Destination=https%3A%2F%2Fconsole.aws.amazon.com%2F HTTP/1.1",
+			expected: "GET /federation?Action=login&SigninToken=REDACTED&Destination=https%3A%2F%2Fconsole.aws.amazon.com%2F HTTP/1.1",
+		},
+		{
+			dump:     "<AccessKeyId>ASIA</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken>",
+			expected: "<AccessKeyId>ASIA</AccessKeyId><SecretAccessKey>REDACTED</SecretAccessKey><SessionToken>REDACTED</SessionToken>",
+		},
+		{
+			dump:     `{"sessionId":"ASIA","sessionKey":"secret","sessionToken":"token"}`,
+			expected: `{"sessionId":"ASIA","sessionKey":"REDACTED","sessionToken":"REDACTED"}`,
+		},
+		{
+			dump:     `{"SigninToken": "token"}`,
+			expected: `{"SigninToken": "REDACTED"}`,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			actual := string(Redact([]byte(test.dump)))
+			if actual != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+// Package httpclient provides the HTTP client used for every request that
+// aws-auth makes, which is carried along with a context.Context.
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Timeouts for each part of a request, so that a stalled connection fails
+// rather than hanging forever.
+const (
+	dialTimeout           = 10 * time.Second
+	keepAlive             = 30 * time.Second
+	tlsHandshakeTimeout   = 10 * time.Second
+	responseHeaderTimeout = 30 * time.Second
+	idleConnTimeout       = 90 * time.Second
+	requestTimeout        = 60 * time.Second
+)
+
+// Client makes every request for a single run, so that connections are kept
+// alive and reused between API calls. Requests are made through any proxy
+// configured with the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment
+// variables, and trust the custom CA bundle named by the AWS_CA_BUNDLE
+// environment variable or a profile's ca_bundle. A Client is safe for
+// concurrent use.
+type Client struct {
+	// static, if set, is used for every request, regardless of CA bundle.
+	static *http.Client
+
+	// roundTripper makes every request, using the transport for the CA
+	// bundle that the request's context.Context carries.
+	roundTripper http.RoundTripper
+
+	lock       sync.Mutex
+	transports map[string]*http.Transport
+	clients    map[string]*http.Client
+}
+
+// New creates a Client.
+func New() *Client {
+	client := &Client{
+		transports: make(map[string]*http.Transport),
+		clients:    make(map[string]*http.Client),
+	}
+	client.roundTripper = bundleRoundTripper{client}
+
+	return client
+}
+
+// Static creates a Client that uses the given *http.Client for every
+// request, ignoring any configured CA bundle.
+func Static(client *http.Client) *Client {
+	return &Client{
+		static: client,
+	}
+}
+
+// Wrap wraps the transport used for every request with the given function,
+// like Debug or Record. It must be called before the Client is first used.
+func (c *Client) Wrap(wrapper func(http.RoundTripper) http.RoundTripper) {
+	c.roundTripper = wrapper(c.roundTripper)
+}
+
+// For returns the *http.Client for making requests that trust the named
+// custom CA bundle, or the system roots if it is empty. The AWS_CA_BUNDLE
+// environment variable takes precedence over the given CA bundle, like with
+// the AWS CLI.
+func (c *Client) For(caBundle string) (*http.Client, error) {
+	if c.static != nil {
+		return c.static, nil
+	}
+
+	if value := os.Getenv("AWS_CA_BUNDLE"); value != "" {
+		caBundle = value
+	}
+
+	// Load the CA bundle now, so that a bad one is reported before any
+	// requests are made.
+	if _, err := c.transport(caBundle); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if client, found := c.clients[caBundle]; found {
+		return client, nil
+	}
+
+	client := &http.Client{
+		Transport: withBundle{caBundle, c.roundTripper},
+		Timeout:   requestTimeout,
+	}
+
+	c.clients[caBundle] = client
+	return client, nil
+}
+
+// transport returns the *http.Transport for the named CA bundle, which is
+// created only once.
+func (c *Client) transport(caBundle string) (*http.Transport, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if transport, found := c.transports[caBundle]; found {
+		return transport, nil
+	}
+
+	transport, err := newTransport(caBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	c.transports[caBundle] = transport
+	return transport, nil
+}
+
+// bundleKey is the context.Context key for the CA bundle of a request.
+type bundleKey struct{}
+
+// withBundle is an http.RoundTripper that marks each request with the CA
+// bundle that it should trust.
+type withBundle struct {
+	caBundle string
+	next     http.RoundTripper
+}
+
+func (w withBundle) RoundTrip(req *http.Request) (*http.Response, error) {
+	return w.next.RoundTrip(req.WithContext(context.WithValue(req.Context(), bundleKey{}, w.caBundle)))
+}
+
+// bundleRoundTripper is an http.RoundTripper that makes each request with the
+// transport for the CA bundle that it is marked with.
+type bundleRoundTripper struct {
+	client *Client
+}
+
+func (b bundleRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	caBundle, _ := req.Context().Value(bundleKey{}).(string)
+
+	transport, err := b.client.transport(caBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	return transport.RoundTrip(req)
+}
+
+// newTransport creates an *http.Transport that trusts the named custom CA
+// bundle, or the system roots if it is empty.
+func newTransport(caBundle string) (*http.Transport, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: keepAlive,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	if caBundle == "" {
+		return transport, nil
+	}
+
+	bundle, err := ioutil.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("ca_bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("ca_bundle: no certificates found in %s", caBundle)
+	}
+
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs: pool,
+	}
+
+	return transport, nil
+}
+
+// clientKey is the context.Context key for the Client.
+type clientKey struct{}
+
+// WithClient returns a copy of the given context.Context, such that any
+// requests made with it use the given Client.
+func WithClient(ctx context.Context, client *Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// defaultClient is used for any requests made without a Client.
+var defaultClient = New()
+
+// FromContext returns the Client carried by the given context.Context, or a
+// shared default Client if there is none.
+func FromContext(ctx context.Context) *Client {
+	if client, ok := ctx.Value(clientKey{}).(*Client); ok {
+		return client
+	}
+	return defaultClient
+}
@@ -0,0 +1,81 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package httpclient
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientFor(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Write the server's self-signed certificate as a CA bundle.
+	bundle := filepath.Join(t.TempDir(), "bundle.pem")
+	body := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(bundle, body, 0600); err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	tests := []struct {
+		env      string
+		caBundle string
+		err      bool
+	}{
+		{
+			err: true,
+		},
+		{
+			caBundle: bundle,
+		},
+		{
+			env: bundle,
+		},
+		{
+			env:      bundle,
+			caBundle: "missing.pem",
+		},
+		{
+			caBundle: "missing.pem",
+			err:      true,
+		},
+	}
+
+	defer os.Setenv("AWS_CA_BUNDLE", os.Getenv("AWS_CA_BUNDLE"))
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			os.Setenv("AWS_CA_BUNDLE", test.env)
+
+			client := New()
+			httpClient, err := client.For(test.caBundle)
+			if err == nil {
+				// The same client is reused for the same CA bundle.
+				if again, _ := client.For(test.caBundle); again != httpClient {
+					t.Fatal("expected client to be reused")
+				}
+
+				var resp *http.Response
+				if resp, err = httpClient.Get(server.URL); err == nil {
+					resp.Body.Close()
+				}
+			}
+
+			switch {
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Interaction is a single recorded request and either its response, or the
+// error that it failed with, with any secrets redacted.
+type Interaction struct {
+	Request  RecordedRequest   `json:"request"`
+	Response *RecordedResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// RecordedRequest is the part of a request that is used for matching it
+// against recorded interactions.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is everything needed to serve a response back.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// volatileParams are STS request parameters that may differ between a
+// recording and its replay, like a session name templated with the time, or
+// a duration that is retried after being rejected. They are ignored when
+// matching requests.
+var volatileParams = []string{"DurationSeconds", "RoleSessionName"}
+
+// key identifies requests that are served the same recorded responses.
+func (r RecordedRequest) key() string {
+	return r.Method + " " + r.URL + "\n" + normalize(r.Body)
+}
+
+// normalize removes any volatile parameters from the given STS request body.
+// Other bodies are returned as-is.
+func normalize(body string) string {
+	values, err := url.ParseQuery(body)
+	if err != nil || values.Get("Action") == "" {
+		return body
+	}
+
+	for _, param := range volatileParams {
+		values.Del(param)
+	}
+
+	return values.Encode()
+}
+
+// recordRequest reads the given request's body, which is replaced so that it
+// can still be sent, and returns the redacted request.
+func recordRequest(req *http.Request) (RecordedRequest, error) {
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    string(Redact([]byte(req.URL.String()))),
+	}
+
+	if req.Body == nil {
+		return recorded, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return recorded, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	recorded.Body = string(Redact(body))
+	return recorded, nil
+}
+
+// recordTransport is an http.RoundTripper that records every interaction.
+type recordTransport struct {
+	next    http.RoundTripper
+	encoder *json.Encoder
+	lock    *sync.Mutex
+}
+
+// Record wraps the given http.RoundTripper, such that every request and
+// response is written to the given writer as a JSON Interaction per line,
+// with any secrets redacted. Requests that fail without a response are
+// recorded with their error.
+func Record(next http.RoundTripper, out io.Writer) http.RoundTripper {
+	// Keep recorded bodies readable, as they are full of XML.
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+
+	return recordTransport{
+		next:    next,
+		encoder: encoder,
+		lock:    &sync.Mutex{},
+	}
+}
+
+func (r recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recordedReq, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		if encodeErr := r.encode(Interaction{
+			Request: recordedReq,
+			Error:   string(Redact([]byte(err.Error()))),
+		}); encodeErr != nil {
+			return nil, encodeErr
+		}
+		return nil, err
+	}
+
+	// Read the whole response body, and replace it so that it can still be
+	// read by the caller.
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	// The recorded body is redacted, so its length no longer matches.
+	header := resp.Header.Clone()
+	header.Del("Content-Length")
+
+	if err := r.encode(Interaction{
+		Request: recordedReq,
+		Response: &RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       string(Redact(body)),
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// encode writes the given interaction as a line of JSON.
+func (r recordTransport) encode(interaction Interaction) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.encoder.Encode(interaction)
+}
+
+// replayTransport is an http.RoundTripper that serves recorded responses,
+// without making any actual requests.
+type replayTransport struct {
+	lock         *sync.Mutex
+	interactions map[string][]Interaction
+}
+
+// Replay returns an http.RoundTripper that serves back the interactions read
+// from the given reader, as written by Record. Each request is matched
+// against the recorded requests by its method, url, and body, with any
+// secrets redacted and any volatile parameters, like the role session name
+// or duration, ignored. Identical requests are served their recorded
+// responses (or errors) in order. A request that matches no remaining
+// recorded request fails.
+func Replay(in io.Reader) (http.RoundTripper, error) {
+	interactions := make(map[string][]Interaction)
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("invalid recorded interaction: %w", err)
+		}
+
+		if interaction.Response == nil && interaction.Error == "" {
+			return nil, fmt.Errorf("invalid recorded interaction: no response or error for %s %s", interaction.Request.Method, interaction.Request.URL)
+		}
+
+		key := interaction.Request.key()
+		interactions[key] = append(interactions[key], interaction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return replayTransport{
+		lock:         &sync.Mutex{},
+		interactions: interactions,
+	}, nil
+}
+
+func (r replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recordedReq, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := recordedReq.key()
+	interactions := r.interactions[key]
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s", recordedReq.Method, recordedReq.URL)
+	}
+	r.interactions[key] = interactions[1:]
+
+	if interactions[0].Response == nil {
+		return nil, fmt.Errorf("recorded error: %s", interactions[0].Error)
+	}
+
+	recorded := interactions[0].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(recorded.Body))),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
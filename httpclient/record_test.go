@@ -0,0 +1,138 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		fmt.Fprintf(w, "<%s><SessionToken>token</SessionToken></%s>", r.PostFormValue("Action"), r.PostFormValue("Action"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		body     string
+		replay   string
+		expected string
+	}{
+		{
+			body:     "Action=AssumeRole&TokenCode=123456",
+			replay:   "Action=AssumeRole&TokenCode=654321",
+			expected: "<AssumeRole><SessionToken>REDACTED</SessionToken></AssumeRole>",
+		},
+		{
+			body:     "Action=GetCallerIdentity",
+			replay:   "Action=GetCallerIdentity",
+			expected: "<GetCallerIdentity><SessionToken>REDACTED</SessionToken></GetCallerIdentity>",
+		},
+		{
+			body:     "Action=GetSessionToken&DurationSeconds=43200&RoleSessionName=alice-20200101T000000Z",
+			replay:   "Action=GetSessionToken&DurationSeconds=3600&RoleSessionName=alice-20210101T000000Z",
+			expected: "<GetSessionToken><SessionToken>REDACTED</SessionToken></GetSessionToken>",
+		},
+	}
+
+	// Record every interaction against the server.
+	var recording bytes.Buffer
+	recorder := &http.Client{
+		Transport: Record(http.DefaultTransport, &recording),
+	}
+	for _, test := range tests {
+		resp, err := recorder.Post(server.URL, "application/x-www-form-urlencoded", strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("expected no error but got error %q", err)
+		}
+		resp.Body.Close()
+	}
+
+	if strings.Contains(recording.String(), "123456") {
+		t.Fatalf("expected recording to be redacted but got %s", recording.String())
+	}
+
+	// Serve the recording back, with the server no longer running.
+	server.Close()
+	transport, err := Replay(&recording)
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+	replayer := &http.Client{
+		Transport: transport,
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			resp, err := replayer.Post(server.URL, "application/x-www-form-urlencoded", strings.NewReader(test.replay))
+			if err != nil {
+				t.Fatalf("expected no error but got error %q", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := ioutil.ReadAll(resp.Body)
+			if string(body) != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, string(body))
+			}
+		})
+	}
+
+	// Each recorded response is only served once.
+	if _, err := replayer.Post(server.URL, "application/x-www-form-urlencoded", strings.NewReader(tests[0].body)); err == nil {
+		t.Fatalf("expected an error but got no error")
+	}
+
+	// Requests with a body that differs other than by volatile parameters
+	// are not matched.
+	if _, err := replayer.Post(server.URL, "application/x-www-form-urlencoded", strings.NewReader("Action=AssumeRole&RoleArn=other")); err == nil {
+		t.Fatalf("expected an error but got no error")
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper implemented by a function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRecordReplayError(t *testing.T) {
+	failing := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("dial tcp 127.0.0.1:443: connect: connection refused")
+	})
+
+	// Record a request that fails without a response.
+	var recording bytes.Buffer
+	recorder := &http.Client{
+		Transport: Record(failing, &recording),
+	}
+	if _, err := recorder.Get("https://sts.amazonaws.com/"); err == nil {
+		t.Fatalf("expected an error but got no error")
+	}
+
+	// Serve the recording back, which fails the same way.
+	transport, err := Replay(&recording)
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+	replayer := &http.Client{
+		Transport: transport,
+	}
+
+	_, err = replayer.Get("https://sts.amazonaws.com/")
+	switch {
+	case err == nil:
+		t.Fatalf("expected an error but got no error")
+	case !strings.Contains(err.Error(), "connection refused"):
+		t.Fatalf("expected recorded error but got %q", err)
+	}
+}
@@ -5,7 +5,7 @@
 package mfa
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -13,10 +13,53 @@ import (
 	ykman "github.com/joshdk/ykmango"
 )
 
+// interacting is held while the user is interacting with a prompt or browser
+// login, so that concurrent interactions happen one at a time.
+var interacting = make(chan struct{}, 1)
+
+// Interact waits for any other interaction with the user to finish, like an
+// MFA prompt, or a SAML role choice or browser login. The returned function
+// must be called once this interaction has finished.
+func Interact(ctx context.Context) (func(), error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case interacting <- struct{}{}:
+		return func() { <-interacting }, nil
+	}
+}
+
+// Prompter provides an MFA code for the given MFA device, without involving
+// the user.
+type Prompter func(ctx context.Context, serial string) (string, error)
+
+// prompterKey is the context.Context key for the Prompter.
+type prompterKey struct{}
+
+// WithPrompter returns a copy of the given context.Context, such that any MFA
+// codes needed with it are provided by the given Prompter, rather than by
+// prompting the user.
+func WithPrompter(ctx context.Context, prompter Prompter) context.Context {
+	return context.WithValue(ctx, prompterKey{}, prompter)
+}
+
 // Prompt requests that the user enter an MFA code. If a Yubikey slot name is
 // given, a code is directly requested from the device, and may require
-// touching the Yubikey.
-func Prompt(serial, message, yubikeySlot string) (string, error) {
+// touching the Yubikey. If the given context.Context carries a Prompter, it
+// provides the code instead. The prompt is abandoned if the given
+// context.Context is canceled.
+func Prompt(ctx context.Context, serial, message, yubikeySlot string) (string, error) {
+	if prompter, ok := ctx.Value(prompterKey{}).(Prompter); ok {
+		return prompter(ctx, serial)
+	}
+
+	// Wait for any other interaction with the user to finish.
+	done, err := Interact(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer done()
+
 	// Print a prompt message so that the user knows what to do.
 	if message != "" {
 		fmt.Fprintf(os.Stderr, "%s ", message)
@@ -24,15 +67,40 @@ func Prompt(serial, message, yubikeySlot string) (string, error) {
 		fmt.Fprintf(os.Stderr, "Enter MFA code for %s: ", serial)
 	}
 
-	if yubikeySlot != "" {
-		// Since the user will not hit enter, print an extra newline.
-		defer fmt.Fprintln(os.Stderr, "")
+	if yubikeySlot == "" {
+		// Read code from the line that the user types in.
+		code, err := ReadLine(ctx)
+		if err != nil && err == ctx.Err() {
+			// End the prompt line, so that any following output is not mangled.
+			fmt.Fprintln(os.Stderr, "")
+		}
+		return strings.TrimSpace(code), err
+	}
 
-		// Generate an MFA code from the Yubikey.
-		return ykman.Generate(yubikeySlot)
+	type result struct {
+		code string
+		err  error
 	}
 
-	// Read code from the line that the user types in.
-	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
-	return strings.TrimSpace(code), err
+	// The Yubikey can't be interrupted, so wait for it in the background. If
+	// the prompt is abandoned, the goroutine ends once the Yubikey is touched
+	// or ykman gives up waiting for it.
+	results := make(chan result, 1)
+	go func() {
+		// Generate an MFA code from the Yubikey.
+		code, err := ykman.Generate(yubikeySlot)
+		results <- result{code, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		// End the prompt line, so that any following output is not mangled.
+		fmt.Fprintln(os.Stderr, "")
+		return "", ctx.Err()
+
+	case result := <-results:
+		// Since the user will not hit enter, print an extra newline.
+		fmt.Fprintln(os.Stderr, "")
+		return result.code, result.err
+	}
 }
@@ -0,0 +1,33 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package mfa
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInteract(t *testing.T) {
+	done, err := Interact(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+
+	// A second interaction must wait for the first to finish.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := Interact(ctx); err == nil {
+		t.Fatalf("expected an error but got no error")
+	}
+
+	done()
+
+	done, err = Interact(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error but got error %q", err)
+	}
+	done()
+}
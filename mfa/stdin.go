@@ -0,0 +1,66 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package mfa
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+// line is a single line read from stdin, or the error that ended reading.
+type line struct {
+	text string
+	err  error
+}
+
+var (
+	// stdinOnce guards starting the goroutine that reads from stdin.
+	stdinOnce sync.Once
+
+	// stdinLines receives each line read from stdin. Once reading fails, the
+	// error is received forever after.
+	stdinLines chan line
+)
+
+// ReadLine reads a line that the user types in, including the trailing
+// newline. Reading is abandoned if the given context.Context is canceled.
+//
+// Stdin can't be interrupted, so it is read by a single goroutine that lives
+// for the rest of the process, rather than one per prompt. This way an
+// abandoned prompt leaves nothing behind, and no input is lost to it. A line
+// typed for an abandoned prompt is instead received by the next one, like
+// type-ahead in a terminal.
+func ReadLine(ctx context.Context) (string, error) {
+	stdinOnce.Do(func() {
+		stdinLines = make(chan line)
+		go readLines(os.Stdin, stdinLines)
+	})
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case line := <-stdinLines:
+		return line.text, line.err
+	}
+}
+
+// readLines sends each line read from the given io.Reader to the given
+// channel, followed by the error that ended reading, forever.
+func readLines(r io.Reader, lines chan<- line) {
+	reader := bufio.NewReader(r)
+	for {
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			for {
+				lines <- line{text, err}
+				text = ""
+			}
+		}
+		lines <- line{text, nil}
+	}
+}
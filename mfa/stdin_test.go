@@ -0,0 +1,61 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package mfa
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadLines(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []line
+	}{
+		{
+			expected: []line{
+				{"", io.EOF},
+				{"", io.EOF},
+			},
+		},
+		{
+			input: "123456\n654321\n",
+			expected: []line{
+				{"123456\n", nil},
+				{"654321\n", nil},
+				{"", io.EOF},
+				{"", io.EOF},
+			},
+		},
+		{
+			input: "123456\npartial",
+			expected: []line{
+				{"123456\n", nil},
+				{"partial", io.EOF},
+				{"", io.EOF},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			lines := make(chan line)
+			go readLines(strings.NewReader(test.input), lines)
+
+			// Every line must be received, even when written all at once.
+			actual := make([]line, len(test.expected))
+			for index := range actual {
+				actual[index] = <-lines
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("expected lines %v but got %v", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package saml
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/joshdk/aws-auth/mfa"
+	"github.com/pkg/browser"
+)
+
+// DefaultListenAddress is the loopback address used for receiving SAML
+// responses when one is not otherwise configured. The IdP application must
+// be configured with a matching ACS url, like http://localhost:35001/saml.
+const DefaultListenAddress = "localhost:35001"
+
+// Capture opens the given IdP login url with the default browser, and waits
+// for the resulting SAML response to be POSTed back to a local assertion
+// consumer service listening on the given address. The base64-encoded SAML
+// assertion is returned. Waiting is abandoned if the given context.Context is
+// canceled.
+func Capture(ctx context.Context, idpURL, address string) (string, error) {
+	if address == "" {
+		address = DefaultListenAddress
+	}
+
+	// Wait for any other interaction with the user to finish, which also
+	// prevents concurrent logins from listening on the same address.
+	done, err := mfa.Interact(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer done()
+
+	// Start listening before opening the browser, so that a quick IdP
+	// redirect can't race us.
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return "", err
+	}
+
+	assertions := make(chan string, 1)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			assertion := r.PostFormValue("SAMLResponse")
+			if assertion == "" {
+				http.Error(w, "missing SAMLResponse", http.StatusBadRequest)
+				return
+			}
+
+			fmt.Fprintln(w, "Login complete! You may close this window.")
+
+			// Only the first assertion is kept, any extras are dropped.
+			select {
+			case assertions <- assertion:
+			default:
+			}
+		}),
+	}
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	// Print a message so that the user knows what to do.
+	fmt.Fprintf(os.Stderr, "Opening browser to complete SAML login...\n")
+	if err := browser.OpenURL(idpURL); err != nil {
+		return "", err
+	}
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case assertion := <-assertions:
+		return assertion, nil
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package saml
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/joshdk/aws-auth/mfa"
+)
+
+// roleAttributeName is the SAML attribute that an IdP uses to communicate
+// which AWS roles the user is permitted to assume.
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_providers_create_saml_assertions.html
+const roleAttributeName = "https://aws.amazon.com/SAML/Attributes/Role"
+
+// Role is a single role/provider pair that was granted in a SAML assertion.
+type Role struct {
+	RoleARN      string
+	PrincipalARN string
+}
+
+// Roles decodes the given base64-encoded SAML assertion, and returns all of
+// the roles that it grants.
+func Roles(assertion string) ([]Role, error) {
+	// response is used for extracting attributes from a SAML response.
+	type response struct {
+		Attributes []struct {
+			Name   string   `xml:"Name,attr"`
+			Values []string `xml:"AttributeValue"`
+		} `xml:"Assertion>AttributeStatement>Attribute"`
+	}
+
+	document, err := base64.StdEncoding.DecodeString(assertion)
+	if err != nil {
+		return nil, fmt.Errorf("malformed saml assertion: %v", err)
+	}
+
+	var resp response
+	if err := xml.Unmarshal(document, &resp); err != nil {
+		return nil, fmt.Errorf("malformed saml assertion: %v", err)
+	}
+
+	var roles []Role
+	for _, attribute := range resp.Attributes {
+		if attribute.Name != roleAttributeName {
+			continue
+		}
+
+		// Each value is a comma separated pair of a role ARN and a
+		// saml-provider ARN, in no particular order.
+		// "arn:aws:iam::000000000000:role/x,arn:aws:iam::000000000000:saml-provider/y"
+		for _, value := range attribute.Values {
+			var role Role
+			for _, part := range strings.Split(value, ",") {
+				part = strings.TrimSpace(part)
+				switch {
+				case strings.Contains(part, ":role/"):
+					role.RoleARN = part
+				case strings.Contains(part, ":saml-provider/"):
+					role.PrincipalARN = part
+				}
+			}
+
+			if role.RoleARN != "" && role.PrincipalARN != "" {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("saml assertion does not grant any roles")
+	}
+
+	return roles, nil
+}
+
+// Choose selects a role from the given list. If a role ARN or principal ARN
+// is given, only roles matching them are considered. Otherwise, if there is
+// more than a single role, the user is prompted to pick one, until the given
+// context.Context is canceled.
+func Choose(ctx context.Context, roles []Role, roleARN, principalARN string) (*Role, error) {
+	if roleARN != "" || principalARN != "" {
+		var matches []Role
+		for _, role := range roles {
+			if (roleARN == "" || role.RoleARN == roleARN) && (principalARN == "" || role.PrincipalARN == principalARN) {
+				matches = append(matches, role)
+			}
+		}
+
+		if len(matches) == 0 {
+			var wanted []string
+			if roleARN != "" {
+				wanted = append(wanted, "role "+roleARN)
+			}
+			if principalARN != "" {
+				wanted = append(wanted, "principal "+principalARN)
+			}
+			return nil, fmt.Errorf("saml assertion does not grant %s", strings.Join(wanted, " with "))
+		}
+		roles = matches
+	}
+
+	if len(roles) == 1 {
+		return &roles[0], nil
+	}
+
+	// Wait for any other interaction with the user to finish.
+	done, err := mfa.Interact(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	// Print a numbered list of roles so that the user knows what to do.
+	for index, role := range roles {
+		fmt.Fprintf(os.Stderr, "[%d] %s\n", index+1, role.RoleARN)
+	}
+	fmt.Fprintf(os.Stderr, "Choose a role: ")
+
+	// Read choice from the line that the user types in.
+	line, err := mfa.ReadLine(ctx)
+	if err != nil {
+		if err == ctx.Err() {
+			// End the prompt line, so that any following output is not mangled.
+			fmt.Fprintln(os.Stderr, "")
+		}
+		return nil, err
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(roles) {
+		return nil, fmt.Errorf("invalid role choice %q", strings.TrimSpace(line))
+	}
+
+	return &roles[choice-1], nil
+}
+
+// ReadFile reads a base64-encoded SAML assertion from the named file.
+func ReadFile(filename string) (string, error) {
+	body, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(body)), nil
+}
+
+// ReadCommand runs the given shell command, and uses its output as a
+// base64-encoded SAML assertion. The command is killed if the given
+// context.Context is canceled.
+func ReadCommand(ctx context.Context, command string) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("saml assertion command failed: %v", err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,146 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package saml
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRoles(t *testing.T) {
+	const template = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion">
+  <saml:Assertion>
+    <saml:AttributeStatement>
+      <saml:Attribute Name="https://aws.amazon.com/SAML/Attributes/RoleSessionName">
+        <saml:AttributeValue>user@example.com</saml:AttributeValue>
+      </saml:Attribute>
+      <saml:Attribute Name="https://aws.amazon.com/SAML/Attributes/Role">%s</saml:Attribute>
+    </saml:AttributeStatement>
+  </saml:Assertion>
+</samlp:Response>`
+
+	tests := []struct {
+		values string
+		roles  []Role
+		err    bool
+	}{
+		{
+			err: true,
+		},
+		{
+			values: `<saml:AttributeValue>arn:aws:iam::000000000000:role/a,arn:aws:iam::000000000000:saml-provider/idp</saml:AttributeValue>`,
+			roles: []Role{
+				{RoleARN: "arn:aws:iam::000000000000:role/a", PrincipalARN: "arn:aws:iam::000000000000:saml-provider/idp"},
+			},
+		},
+		{
+			values: `<saml:AttributeValue>arn:aws:iam::000000000000:saml-provider/idp,arn:aws:iam::000000000000:role/a</saml:AttributeValue>
+				<saml:AttributeValue>arn:aws:iam::111111111111:role/b, arn:aws:iam::111111111111:saml-provider/idp</saml:AttributeValue>`,
+			roles: []Role{
+				{RoleARN: "arn:aws:iam::000000000000:role/a", PrincipalARN: "arn:aws:iam::000000000000:saml-provider/idp"},
+				{RoleARN: "arn:aws:iam::111111111111:role/b", PrincipalARN: "arn:aws:iam::111111111111:saml-provider/idp"},
+			},
+		},
+		{
+			values: `<saml:AttributeValue>arn:aws:iam::000000000000:role/a</saml:AttributeValue>`,
+			err:    true,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			assertion := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(template, test.values)))
+
+			roles, err := Roles(assertion)
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if !reflect.DeepEqual(roles, test.roles) {
+				t.Fatalf("expected roles %v but got %v", test.roles, roles)
+			}
+		})
+	}
+}
+
+func TestChoose(t *testing.T) {
+	roles := []Role{
+		{RoleARN: "arn:aws:iam::000000000000:role/a", PrincipalARN: "arn:aws:iam::000000000000:saml-provider/idp"},
+		{RoleARN: "arn:aws:iam::000000000000:role/a", PrincipalARN: "arn:aws:iam::000000000000:saml-provider/other"},
+		{RoleARN: "arn:aws:iam::111111111111:role/b", PrincipalARN: "arn:aws:iam::111111111111:saml-provider/idp"},
+	}
+
+	tests := []struct {
+		roles        []Role
+		roleARN      string
+		principalARN string
+		role         Role
+		err          bool
+	}{
+		{
+			roles: roles[2:],
+			role:  roles[2],
+		},
+		{
+			roles:        roles,
+			roleARN:      "arn:aws:iam::000000000000:role/a",
+			principalARN: "arn:aws:iam::000000000000:saml-provider/other",
+			role:         roles[1],
+		},
+		{
+			roles:        roles,
+			roleARN:      "arn:aws:iam::111111111111:role/b",
+			principalARN: "arn:aws:iam::111111111111:saml-provider/idp",
+			role:         roles[2],
+		},
+		{
+			roles:   roles,
+			roleARN: "arn:aws:iam::111111111111:role/b",
+			role:    roles[2],
+		},
+		{
+			roles:        roles,
+			principalARN: "arn:aws:iam::000000000000:saml-provider/other",
+			role:         roles[1],
+		},
+		{
+			roles:        roles,
+			roleARN:      "arn:aws:iam::111111111111:role/b",
+			principalARN: "arn:aws:iam::000000000000:saml-provider/idp",
+			err:          true,
+		},
+		{
+			roles:   roles,
+			roleARN: "arn:aws:iam::222222222222:role/c",
+			err:     true,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			role, err := Choose(context.Background(), test.roles, test.roleARN, test.principalARN)
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if *role != test.role {
+				t.Fatalf("expected role %v but got %v", test.role, *role)
+			}
+		})
+	}
+}
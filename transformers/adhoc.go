@@ -0,0 +1,103 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joshdk/aws-auth/config"
+)
+
+// adHocProfile is the profile name used for transforms that were given on
+// the command line, rather than in a config file.
+const adHocProfile = "(ad-hoc)"
+
+// AdHocOptions describes a chain of transforms given on the command line,
+// rather than in a config file.
+type AdHocOptions struct {
+	// Via is a list of hops, performed in order. Each hop is one of:
+	// "role:ARN" - Assume the given role.
+	// "session" - Get a session token.
+	// "session:MFA_SERIAL" - Get a session token, using the given MFA device.
+	Via []string
+
+	// RoleARN is a final role to assume, after all other hops.
+	RoleARN string
+
+	// ExternalID is the external id used when assuming RoleARN.
+	ExternalID string
+
+	// MFASerial is the MFA device used when assuming RoleARN.
+	MFASerial string
+}
+
+// AdHoc creates a chain of transforms from the given options. API calls are
+// made against the given config.Endpoint, and the first transform uses
+// credentials from the named source profile.
+func AdHoc(source string, endpoint config.Endpoint, options AdHocOptions) ([]Transformer, error) {
+	if options.RoleARN == "" && (options.ExternalID != "" || options.MFASerial != "") {
+		return nil, fmt.Errorf("--external-id and --mfa-serial require --role-arn")
+	}
+
+	hops := append([]string{}, options.Via...)
+	if options.RoleARN != "" {
+		hops = append(hops, "role:"+options.RoleARN)
+	}
+
+	var chain []Transformer
+	for index, hop := range hops {
+		// Split the hop into its kind and argument. The argument may contain
+		// further colons, as it's usually an ARN.
+		// "role:arn:aws:iam::000000000000:role/admin" → "role", "arn:aws:..."
+		kind, argument := hop, ""
+		if sep := strings.Index(hop, ":"); sep >= 0 {
+			kind, argument = hop[:sep], hop[sep+1:]
+		}
+
+		switch kind {
+		case "role":
+			if argument == "" {
+				return nil, fmt.Errorf("invalid hop %q, a role arn is required", hop)
+			}
+
+			role := config.Role{
+				DurationSeconds: int(defaultDuration.Seconds()),
+				Endpoint:        endpoint,
+				RoleARN:         argument,
+				SourceProfile:   source,
+			}
+
+			// The external id and MFA device only apply to the final role.
+			if options.RoleARN != "" && index == len(hops)-1 {
+				role.ExternalID = options.ExternalID
+				role.MFASerial = options.MFASerial
+			}
+
+			chain = append(chain, AssumeRoleTransform{
+				Profile: adHocProfile,
+				Role:    &role,
+			})
+
+		case "session":
+			chain = append(chain, SessionTokenTransform{
+				Profile: adHocProfile,
+				Session: &config.Session{
+					DurationSeconds: int(defaultDuration.Seconds()),
+					Endpoint:        endpoint,
+					MFASerial:       argument,
+					SourceProfile:   source,
+				},
+			})
+
+		default:
+			return nil, fmt.Errorf("invalid hop %q, expected role:ARN or session", hop)
+		}
+
+		source = adHocProfile
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,93 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/joshdk/aws-auth/config"
+)
+
+func TestAdHoc(t *testing.T) {
+	tests := []struct {
+		options AdHocOptions
+		chain   []Transformer
+		err     bool
+	}{
+		{},
+		{
+			options: AdHocOptions{
+				Via:        []string{"session:arn:aws:iam::000000000000:mfa/user", "role:arn:aws:iam::000000000000:role/a"},
+				RoleARN:    "arn:aws:iam::111111111111:role/b",
+				ExternalID: "external",
+			},
+			chain: []Transformer{
+				SessionTokenTransform{
+					Profile: adHocProfile,
+					Session: &config.Session{
+						DurationSeconds: 3600,
+						MFASerial:       "arn:aws:iam::000000000000:mfa/user",
+						SourceProfile:   "default",
+					},
+				},
+				AssumeRoleTransform{
+					Profile: adHocProfile,
+					Role: &config.Role{
+						DurationSeconds: 3600,
+						RoleARN:         "arn:aws:iam::000000000000:role/a",
+						SourceProfile:   adHocProfile,
+					},
+				},
+				AssumeRoleTransform{
+					Profile: adHocProfile,
+					Role: &config.Role{
+						DurationSeconds: 3600,
+						ExternalID:      "external",
+						RoleARN:         "arn:aws:iam::111111111111:role/b",
+						SourceProfile:   adHocProfile,
+					},
+				},
+			},
+		},
+		{
+			options: AdHocOptions{
+				MFASerial: "arn:aws:iam::000000000000:mfa/user",
+			},
+			err: true,
+		},
+		{
+			options: AdHocOptions{
+				Via: []string{"role"},
+			},
+			err: true,
+		},
+		{
+			options: AdHocOptions{
+				Via: []string{"federate"},
+			},
+			err: true,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			chain, err := AdHoc("default", config.Endpoint{}, test.options)
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if !reflect.DeepEqual(chain, test.chain) {
+				t.Fatalf("expected chain %+v but got %+v", test.chain, chain)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/saml"
+)
+
+type SAMLTransform struct {
+	Profile string
+	SAML    *config.SAML
+}
+
+// Transform obtains a SAML assertion using the internal config.SAML and
+// performs an AssumeRoleWithSAML. The input sts.Credentials are ignored, as
+// the assertion itself is used for authentication. The sts.Credentials for
+// the assumed role are returned.
+func (s SAMLTransform) Transform(ctx context.Context, _ *sts.Credentials) (*sts.Credentials, error) {
+	// Obtain an assertion, preferring non-interactive sources.
+	var assertion string
+	var err error
+	switch {
+	case s.SAML.AssertionFile != "":
+		assertion, err = saml.ReadFile(s.SAML.AssertionFile)
+	case s.SAML.AssertionCommand != "":
+		assertion, err = saml.ReadCommand(ctx, s.SAML.AssertionCommand)
+	default:
+		assertion, err = saml.Capture(ctx, s.SAML.IdPURL, s.SAML.ListenAddress)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine which of the granted roles to assume.
+	roles, err := saml.Roles(assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := saml.Choose(ctx, roles, s.SAML.RoleARN, s.SAML.PrincipalARN)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pack the input struct with appropriate data. Fields that have a
+	// zero-value must be nil (opposed if a pointer to a zero-value).
+	input := sts.AssumeRoleWithSAMLInput{
+		PrincipalArn:  aws.String(role.PrincipalARN),
+		RoleArn:       aws.String(role.RoleARN),
+		SAMLAssertion: aws.String(assertion),
+	}
+
+	if value := s.SAML.Policy; value != "" {
+		input.Policy = aws.String(value)
+	}
+
+	for _, value := range s.SAML.PolicyARNs {
+		input.PolicyArns = append(input.PolicyArns, &sts.PolicyDescriptorType{
+			Arn: aws.String(value),
+		})
+	}
+
+	// Create a client without any credentials, as AssumeRoleWithSAML is an
+	// unsigned API call.
+	client, err := newClient(ctx, nil, s.SAML.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform the actual API call, negotiating a shorter duration if the
+	// role does not allow the one that was configured.
+	var result *sts.AssumeRoleWithSAMLOutput
+	err = negotiateDuration(ctx, s.Profile, s.SAML.DurationSeconds, false, func(seconds int64) error {
+		input.DurationSeconds = aws.Int64(seconds)
+		output, err := client.AssumeRoleWithSAMLWithContext(ctx, &input)
+		result = output
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Return new credentials for this role!
+	return result.Credentials, nil
+}
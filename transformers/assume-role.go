@@ -5,11 +5,11 @@
 package transformers
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/joshdk/aws-auth/config"
 	"github.com/joshdk/aws-auth/mfa"
@@ -21,25 +21,20 @@ const (
 )
 
 type AssumeRoleTransform struct {
-	Role *config.Role
+	Profile string
+	Role    *config.Role
 }
 
 // Transform takes the input sts.Credentials and the internal config.Role and
 // performs an AssumeRole. The sts.Credentials for the assumed role are
 // returned.
-func (s AssumeRoleTransform) Transform(creds *sts.Credentials) (*sts.Credentials, error) {
+func (s AssumeRoleTransform) Transform(ctx context.Context, creds *sts.Credentials) (*sts.Credentials, error) {
 	// Pack the input struct with appropriate data. Fields that have a
 	// zero-value must be nil (opposed if a pointer to a zero-value).
 	input := sts.AssumeRoleInput{
 		RoleArn: aws.String(s.Role.RoleARN),
 	}
 
-	if value := s.Role.DurationSeconds; value != 0 {
-		input.DurationSeconds = aws.Int64(int64(value))
-	} else {
-		input.DurationSeconds = aws.Int64(int64(defaultDuration.Seconds()))
-	}
-
 	if value := s.Role.ExternalID; value != "" {
 		input.ExternalId = aws.String(value)
 	}
@@ -59,14 +54,29 @@ func (s AssumeRoleTransform) Transform(creds *sts.Credentials) (*sts.Credentials
 	}
 
 	if value := s.Role.RoleSessionName; value != "" {
-		input.RoleSessionName = aws.String(value)
+		// Render the session name, which may be a template.
+		name, err := sessionName(ctx, value, creds, s.Role.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("role_session_name: %v", err)
+		}
+		input.RoleSessionName = aws.String(name)
 	} else {
 		input.RoleSessionName = aws.String(defaultRoleSessionName)
 	}
 
+	if value := s.Role.SourceIdentity; value != "" {
+		input.SourceIdentity = aws.String(value)
+	}
+
+	input.Tags = tags(s.Role.Tags)
+
+	for _, value := range s.Role.TransitiveTagKeys {
+		input.TransitiveTagKeys = append(input.TransitiveTagKeys, aws.String(value))
+	}
+
 	if s.Role.MFASerial != "" {
 		// Prompt the user to enter an MFA code.
-		code, err := mfa.Prompt(s.Role.MFASerial, s.Role.MFAMessage, s.Role.YubikeySlot)
+		code, err := mfa.Prompt(ctx, s.Role.MFASerial, s.Role.MFAMessage, s.Role.YubikeySlot)
 		if err != nil {
 			return nil, err
 		}
@@ -74,21 +84,22 @@ func (s AssumeRoleTransform) Transform(creds *sts.Credentials) (*sts.Credentials
 		input.TokenCode = aws.String(code)
 	}
 
-	// Create a session with the input credentials that will be used in the
+	// Create a client with the input credentials that will be used in the
 	// following API call.
-	sess, err := session.NewSession(&aws.Config{
-		Credentials: credentials.NewStaticCredentials(
-			aws.StringValue(creds.AccessKeyId),
-			aws.StringValue(creds.SecretAccessKey),
-			aws.StringValue(creds.SessionToken),
-		),
-	})
+	client, err := newClient(ctx, creds, s.Role.Endpoint)
 	if err != nil {
 		return nil, err
 	}
 
-	// Perform the actual API call.
-	result, err := sts.New(sess).AssumeRole(&input)
+	// Perform the actual API call, negotiating a shorter duration if the
+	// role does not allow the one that was configured.
+	var result *sts.AssumeRoleOutput
+	err = negotiateDuration(ctx, s.Profile, s.Role.DurationSeconds, input.TokenCode != nil, func(seconds int64) error {
+		input.DurationSeconds = aws.Int64(seconds)
+		output, err := client.AssumeRoleWithContext(ctx, &input)
+		result = output
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -29,11 +29,11 @@ func Chain(cfg *config.Config, profile string) (*sts.Credentials, []Transformer,
 
 func chain(cfg *config.Config, profile string, seen map[string]struct{}) (*sts.Credentials, []Transformer, error) {
 	// Look up the named profile. Maybe it's a user? Maybe it's a role?
-	maybeUser, maybeRole, maybeSession, maybeFederate, err := cfg.Profile(profile)
+	maybeUser, maybeRole, maybeSession, maybeFederate, maybeSAML, err := cfg.Profile(profile)
 	if err != nil {
 		return nil, nil, chainError{
 			profile: profile,
-			err:     err,
+			err:     profileError(cfg, profile, err),
 		}
 	}
 
@@ -49,6 +49,17 @@ func chain(cfg *config.Config, profile string, seen map[string]struct{}) (*sts.C
 
 		return &creds, nil, nil
 
+	case maybeSAML != nil:
+		// We have found a SAML login. Like a user, this is the start of the
+		// chain, but there are no initial credentials as the SAML assertion
+		// is used for authentication instead.
+		transform := SAMLTransform{
+			Profile: profile,
+			SAML:    maybeSAML,
+		}
+
+		return nil, []Transformer{transform}, nil
+
 	case maybeFederate != nil:
 		// We have found a federate. Check that we have not visited this profile
 		// already, as that would mean that there is a circular profile
@@ -56,7 +67,7 @@ func chain(cfg *config.Config, profile string, seen map[string]struct{}) (*sts.C
 		if _, found := seen[maybeFederate.SourceProfile]; found {
 			return nil, nil, chainError{
 				profile: profile,
-				err:     fmt.Errorf("recursive profile"),
+				err:     &Error{Kind: KindConfig, Err: fmt.Errorf("recursive profile")},
 			}
 		}
 		seen[maybeFederate.SourceProfile] = struct{}{}
@@ -74,6 +85,7 @@ func chain(cfg *config.Config, profile string, seen map[string]struct{}) (*sts.C
 		// Create a get-federation-token transformer for this profile, and add
 		// it to the chain.
 		transform := FederationTokenTransform{
+			Profile:  profile,
 			Federate: maybeFederate,
 		}
 		chain = append(chain, transform)
@@ -87,7 +99,7 @@ func chain(cfg *config.Config, profile string, seen map[string]struct{}) (*sts.C
 		if _, found := seen[maybeRole.SourceProfile]; found {
 			return nil, nil, chainError{
 				profile: profile,
-				err:     fmt.Errorf("recursive profile"),
+				err:     &Error{Kind: KindConfig, Err: fmt.Errorf("recursive profile")},
 			}
 		}
 		seen[maybeRole.SourceProfile] = struct{}{}
@@ -105,7 +117,8 @@ func chain(cfg *config.Config, profile string, seen map[string]struct{}) (*sts.C
 		// Create an assume-role transformer for this profile, and add it to
 		// the chain.
 		transform := AssumeRoleTransform{
-			Role: maybeRole,
+			Profile: profile,
+			Role:    maybeRole,
 		}
 		chain = append(chain, transform)
 
@@ -118,7 +131,7 @@ func chain(cfg *config.Config, profile string, seen map[string]struct{}) (*sts.C
 		if _, found := seen[maybeSession.SourceProfile]; found {
 			return nil, nil, chainError{
 				profile: profile,
-				err:     fmt.Errorf("recursive profile"),
+				err:     &Error{Kind: KindConfig, Err: fmt.Errorf("recursive profile")},
 			}
 		}
 		seen[maybeSession.SourceProfile] = struct{}{}
@@ -136,6 +149,7 @@ func chain(cfg *config.Config, profile string, seen map[string]struct{}) (*sts.C
 		// Create an assume-role transformer for this profile, and add it to
 		// the chain.
 		transform := SessionTokenTransform{
+			Profile: profile,
 			Session: maybeSession,
 		}
 		chain = append(chain, transform)
@@ -155,6 +169,10 @@ func (e chainError) Error() string {
 	return "profile chain " + e.error()
 }
 
+func (e chainError) Unwrap() error {
+	return e.err
+}
+
 func (e chainError) error() string {
 	switch cerr := e.err.(type) {
 	case chainError:
@@ -0,0 +1,145 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/httpclient"
+)
+
+// retryer retries throttled and otherwise retryable API calls, with jittered
+// exponential backoff. API calls made with an MFA code are never retried.
+var retryer = client.DefaultRetryer{
+	NumMaxRetries:    5,
+	MinRetryDelay:    100 * time.Millisecond,
+	MaxRetryDelay:    5 * time.Second,
+	MinThrottleDelay: 500 * time.Millisecond,
+	MaxThrottleDelay: 20 * time.Second,
+}
+
+// baseSession is shared by every STS client, so that the environment and
+// shared config are only read once. It is created on first use, and creation
+// is retried on later uses if it fails, as the environment may be fixed by
+// then.
+var baseSession struct {
+	lock sync.Mutex
+	sess *session.Session
+}
+
+// getBaseSession returns the shared base session, creating it if needed.
+func getBaseSession() (*session.Session, error) {
+	baseSession.lock.Lock()
+	defer baseSession.lock.Unlock()
+
+	if baseSession.sess != nil {
+		return baseSession.sess, nil
+	}
+
+	// The SDK applies any custom CA bundle from the environment to the
+	// session's own client, which is never used, as the httpclient.Client
+	// handles CA bundles itself.
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.AnonymousCredentials,
+		HTTPClient:  &http.Client{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	baseSession.sess = sess
+	return sess, nil
+}
+
+// newClient creates an STS client that makes API calls using the given
+// sts.Credentials, against the STS endpoint described by the given
+// config.Endpoint. If the given credentials are nil, API calls are unsigned.
+// Requests are made with the httpclient.Client carried by the given
+// context.Context.
+func newClient(ctx context.Context, creds *sts.Credentials, endpoint config.Endpoint) (*sts.STS, error) {
+	sess, err := getBaseSession()
+	if err != nil {
+		return nil, &Error{Kind: KindConfig, Err: err}
+	}
+
+	httpClient, err := httpclient.FromContext(ctx).For(endpoint.CABundle)
+	if err != nil {
+		return nil, &Error{Kind: KindConfig, Err: err}
+	}
+
+	cfg := aws.Config{
+		Credentials: credentials.AnonymousCredentials,
+		HTTPClient:  httpClient,
+		Retryer:     retryer,
+	}
+
+	if creds != nil {
+		cfg.Credentials = credentials.NewStaticCredentials(
+			aws.StringValue(creds.AccessKeyId),
+			aws.StringValue(creds.SecretAccessKey),
+			aws.StringValue(creds.SessionToken),
+		)
+	}
+
+	if value := endpoint.Region; value != "" {
+		cfg.Region = aws.String(value)
+	}
+
+	if value := endpoint.EndpointURL; value != "" {
+		cfg.Endpoint = aws.String(value)
+	}
+
+	if value := endpoint.STSRegionalEndpoints; value != "" {
+		stsRegionalEndpoint, err := endpoints.GetSTSRegionalEndpoint(value)
+		if err != nil {
+			return nil, err
+		}
+		cfg.STSRegionalEndpoint = stsRegionalEndpoint
+	}
+
+	if endpoint.UseDualStackEndpoint {
+		cfg.UseDualStackEndpoint = endpoints.DualStackEndpointStateEnabled
+	}
+
+	if endpoint.UseFIPSEndpoint {
+		cfg.UseFIPSEndpoint = endpoints.FIPSEndpointStateEnabled
+	}
+
+	svc := sts.New(sess, &cfg)
+
+	// MFA codes can only be used once, so API calls made with one are never
+	// retried, as every retry would be rejected.
+	svc.Handlers.Build.PushBack(func(r *request.Request) {
+		if hasTokenCode(r.Params) {
+			r.Retryer = client.NoOpRetryer{}
+		}
+	})
+
+	return svc, nil
+}
+
+// hasTokenCode reports whether the given API call input includes an MFA
+// code.
+func hasTokenCode(params interface{}) bool {
+	switch input := params.(type) {
+	case *sts.AssumeRoleInput:
+		return input.TokenCode != nil
+	case *sts.GetSessionTokenInput:
+		return input.TokenCode != nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+)
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		input    sts.AssumeRoleInput
+		attempts int32
+	}{
+		{
+			input: sts.AssumeRoleInput{
+				RoleArn:         aws.String("arn:aws:iam::000000000000:role/admin"),
+				RoleSessionName: aws.String("test"),
+			},
+			attempts: 3,
+		},
+		{
+			input: sts.AssumeRoleInput{
+				RoleArn:         aws.String("arn:aws:iam::000000000000:role/admin"),
+				RoleSessionName: aws.String("test"),
+				SerialNumber:    aws.String("arn:aws:iam::000000000000:mfa/alice"),
+				TokenCode:       aws.String("123456"),
+			},
+			attempts: 1,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			// Fail every request with a retryable error.
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			svc, err := newClient(context.Background(), &sts.Credentials{
+				AccessKeyId:     aws.String("AKIAALICE"),
+				SecretAccessKey: aws.String("secret"),
+			}, config.Endpoint{
+				EndpointURL: server.URL,
+				Region:      "us-east-1",
+			})
+			if err != nil {
+				t.Fatalf("expected no error but got error %q", err)
+			}
+
+			// Retry quickly, so that the test does not take long.
+			fast := func(r *request.Request) {
+				r.Retryer = client.DefaultRetryer{
+					NumMaxRetries:    2,
+					MinRetryDelay:    time.Millisecond,
+					MaxRetryDelay:    time.Millisecond,
+					MinThrottleDelay: time.Millisecond,
+					MaxThrottleDelay: time.Millisecond,
+				}
+			}
+
+			input := test.input
+			if _, err := svc.AssumeRoleWithContext(context.Background(), &input, fast); err == nil {
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if attempts != test.attempts {
+				t.Fatalf("expected %d attempts but got %d", test.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestClientRecovers(t *testing.T) {
+	tests := []struct {
+		caBundle string
+		exitCode int
+	}{
+		{
+			caBundle: "testdata/missing.pem",
+			exitCode: 2,
+		},
+		{
+			caBundle: "",
+		},
+	}
+
+	// Start without a base session, so that the first client creates it.
+	baseSession.sess = nil
+
+	defer os.Setenv("AWS_CA_BUNDLE", os.Getenv("AWS_CA_BUNDLE"))
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			os.Setenv("AWS_CA_BUNDLE", test.caBundle)
+
+			_, err := newClient(context.Background(), nil, config.Endpoint{Region: "us-east-1"})
+			switch {
+			case err != nil && test.exitCode == 0:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.exitCode != 0:
+				t.Fatalf("expected an error but got no error")
+			case err != nil:
+				if code := ExitCode(err); code != test.exitCode {
+					t.Fatalf("expected exit code %d but got %d", test.exitCode, code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/joshdk/aws-auth/config"
+)
+
+// durationCandidates are the durations tried, longest first, when STS rejects
+// a duration as being longer than the role allows. A role allows at most
+// somewhere between 1 and 12 hours, so these find a duration at or close to
+// its limit within a few calls.
+var durationCandidates = []time.Duration{
+	12 * time.Hour,
+	8 * time.Hour,
+	4 * time.Hour,
+	2 * time.Hour,
+	1 * time.Hour,
+}
+
+// negotiateDuration calls the given function with the configured duration,
+// for an API call that assumes a role. If STS rejects the duration as being
+// longer than the role allows, the call is retried with each shorter
+// candidate duration until one is accepted, and the downgrade is reported as
+// a notice. If the role is being assumed via role chaining, the call is
+// retried with 1 hour straight away, as that is the limit for every role.
+//
+// STS does not say what the role's maximum session duration is, so it is
+// found by trial. Calls made with an MFA code are never retried, as the code
+// can only be used once.
+func negotiateDuration(ctx context.Context, profile string, configured int, withMFA bool, call func(seconds int64) error) error {
+	seconds := int64(configured)
+	switch configured {
+	case 0:
+		seconds = int64(defaultDuration.Seconds())
+	case config.MaxDuration:
+		seconds = int64(maxRoleDuration.Seconds())
+	}
+
+	err := call(seconds)
+	if err == nil || !durationRejected(err, seconds) {
+		return err
+	}
+
+	if withMFA {
+		return &Error{
+			Kind: KindConfig,
+			Hint: "lower duration_seconds to at most the role's maximum session duration, as the MFA code can not be reused for a retry",
+			Err:  err,
+		}
+	}
+
+	// Try each shorter duration in turn, until one is accepted.
+	accepted := seconds
+	for _, candidate := range durationCandidates {
+		fallback := int64(candidate.Seconds())
+		if fallback >= accepted {
+			continue
+		}
+
+		// Role chaining limits sessions to 1 hour, whatever the role allows.
+		if chained(err) && candidate != maxChainedRoleDuration {
+			continue
+		}
+
+		accepted = fallback
+		if err = call(fallback); err == nil || !durationRejected(err, fallback) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only report a downgrade if a specific duration was asked for.
+	if configured != config.MaxDuration {
+		notice(ctx, "profile %s: duration of %s is longer than the role allows, using %s",
+			profile, time.Duration(seconds)*time.Second, time.Duration(accepted)*time.Second)
+	}
+
+	return nil
+}
+
+// chained reports whether the given error is STS rejecting a duration for a
+// role assumed via role chaining.
+// "The requested DurationSeconds exceeds the 1 hour session limit for roles
+// assumed by role chaining."
+func chained(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && strings.Contains(aerr.Message(), "role chaining")
+}
+
+// durationRejected reports whether the given error is STS rejecting the
+// given duration as being longer than the role allows, and whether retrying
+// with a shorter duration could succeed.
+func durationRejected(err error, seconds int64) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok || aerr.Code() != "ValidationError" || !strings.Contains(aerr.Message(), "DurationSeconds") {
+		return false
+	}
+
+	// "The requested DurationSeconds exceeds the 1 hour session limit for
+	// roles assumed by role chaining."
+	// "The requested DurationSeconds exceeds the MaxSessionDuration set for
+	// this role."
+	if !chained(err) && !strings.Contains(aerr.Message(), "MaxSessionDuration") {
+		return false
+	}
+
+	// Every role allows sessions of 1 hour, so a duration that short was
+	// rejected for some other reason.
+	return seconds > int64(maxChainedRoleDuration.Seconds())
+}
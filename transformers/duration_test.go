@@ -0,0 +1,126 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/joshdk/aws-auth/config"
+)
+
+func TestNegotiateDuration(t *testing.T) {
+	maxSession := awserr.New("ValidationError", "The requested DurationSeconds exceeds the MaxSessionDuration set for this role.", nil)
+	chained := awserr.New("ValidationError", "The requested DurationSeconds exceeds the 1 hour session limit for roles assumed by role chaining.", nil)
+
+	tests := []struct {
+		configured int
+		withMFA    bool
+		allowed    int64
+		err        error
+		attempts   []int64
+		notice     string
+		fails      bool
+	}{
+		{
+			configured: 3600,
+			allowed:    43200,
+			attempts:   []int64{3600},
+		},
+		{
+			configured: 0,
+			allowed:    43200,
+			attempts:   []int64{3600},
+		},
+		{
+			configured: 14400,
+			allowed:    7200,
+			err:        maxSession,
+			attempts:   []int64{14400, 7200},
+			notice:     "aws-auth: profile test: duration of 4h0m0s is longer than the role allows, using 2h0m0s\n",
+		},
+		{
+			configured: config.MaxDuration,
+			allowed:    7200,
+			err:        maxSession,
+			attempts:   []int64{43200, 28800, 14400, 7200},
+		},
+		{
+			configured: config.MaxDuration,
+			allowed:    36000,
+			err:        maxSession,
+			attempts:   []int64{43200, 28800},
+		},
+		{
+			configured: 36000,
+			allowed:    3600,
+			err:        maxSession,
+			attempts:   []int64{36000, 28800, 14400, 7200, 3600},
+			notice:     "aws-auth: profile test: duration of 10h0m0s is longer than the role allows, using 1h0m0s\n",
+		},
+		{
+			configured: config.MaxDuration,
+			allowed:    3600,
+			err:        chained,
+			attempts:   []int64{43200, 3600},
+		},
+		{
+			configured: 14400,
+			withMFA:    true,
+			allowed:    7200,
+			err:        maxSession,
+			attempts:   []int64{14400},
+			fails:      true,
+		},
+		{
+			configured: 3600,
+			allowed:    900,
+			err:        maxSession,
+			attempts:   []int64{3600},
+			fails:      true,
+		},
+		{
+			configured: 7200,
+			err:        errors.New("AccessDenied"),
+			attempts:   []int64{7200},
+			fails:      true,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			var notices bytes.Buffer
+			ctx := WithNotices(context.Background(), &notices)
+
+			var attempts []int64
+			err := negotiateDuration(ctx, "test", test.configured, test.withMFA, func(seconds int64) error {
+				attempts = append(attempts, seconds)
+				if seconds > test.allowed {
+					return test.err
+				}
+				return nil
+			})
+
+			switch {
+			case err != nil && !test.fails:
+				t.Fatalf("expected no error but got error %q", err)
+			case test.fails && !errors.Is(err, test.err):
+				t.Fatalf("expected error %q but got %v", test.err, err)
+			}
+
+			if fmt.Sprint(attempts) != fmt.Sprint(test.attempts) {
+				t.Fatalf("expected attempts %v but got %v", test.attempts, attempts)
+			}
+
+			if notices.String() != test.notice {
+				t.Fatalf("expected notice %q but got %q", test.notice, notices.String())
+			}
+		})
+	}
+}
@@ -5,12 +5,12 @@
 package transformers
 
 import (
+	"context"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
 )
 
 type Identity struct {
@@ -18,6 +18,7 @@ type Identity struct {
 	AccessKeyID     string
 	AccountID       string
 	Expiration      time.Time
+	Region          string
 	SecretAccessKey string
 	SessionToken    string
 }
@@ -37,6 +38,12 @@ func (i Identity) Env() map[string]string {
 		vars["AWS_EXPIRATION"] = i.Expiration.String()
 	}
 
+	// Region is only known if the profile configured one.
+	if i.Region != "" {
+		vars["AWS_DEFAULT_REGION"] = i.Region
+		vars["AWS_REGION"] = i.Region
+	}
+
 	// IAM keys do not have an associated session token.
 	if i.SessionToken != "" {
 		vars["AWS_SESSION_TOKEN"] = i.SessionToken
@@ -46,23 +53,18 @@ func (i Identity) Env() map[string]string {
 }
 
 // Enrich combines the given sts.Credentials with information about their
-// associated principal for convenience.
-func Enrich(creds *sts.Credentials) (*Identity, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Credentials: credentials.NewStaticCredentials(
-			aws.StringValue(creds.AccessKeyId),
-			aws.StringValue(creds.SecretAccessKey),
-			aws.StringValue(creds.SessionToken),
-		),
-	})
+// associated principal for convenience. The API call is made against the
+// given config.Endpoint.
+func Enrich(ctx context.Context, creds *sts.Credentials, endpoint config.Endpoint) (*Identity, error) {
+	client, err := newClient(ctx, creds, endpoint)
 	if err != nil {
 		return nil, err
 	}
 
 	// Perform the actual API call.
-	output, err := sts.New(sess).GetCallerIdentity(nil)
+	output, err := client.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
-		return nil, err
+		return nil, classify(err)
 	}
 
 	return &Identity{
@@ -70,6 +72,7 @@ func Enrich(creds *sts.Credentials) (*Identity, error) {
 		AccessKeyID:     aws.StringValue(creds.AccessKeyId),
 		AccountID:       aws.StringValue(output.Account),
 		Expiration:      aws.TimeValue(creds.Expiration),
+		Region:          endpoint.Region,
 		SecretAccessKey: aws.StringValue(creds.SecretAccessKey),
 		SessionToken:    aws.StringValue(creds.SessionToken),
 	}, nil
@@ -0,0 +1,175 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/joshdk/aws-auth/config"
+)
+
+// Kind classifies why obtaining credentials failed.
+type Kind int
+
+// Kinds of failures. Each has a distinct process exit code, so that scripts
+// can tell them apart.
+const (
+	KindUnknown Kind = iota
+	KindConfig
+	KindUnknownProfile
+	KindAccessDenied
+	KindMFA
+	KindExpired
+	KindInvalidCredentials
+	KindClockSkew
+	KindThrottled
+	KindNetwork
+	KindTimeout
+	KindCanceled
+)
+
+// exitCodes maps each Kind to a process exit code.
+var exitCodes = map[Kind]int{
+	KindUnknown:            1,
+	KindConfig:             2,
+	KindUnknownProfile:     3,
+	KindAccessDenied:       4,
+	KindMFA:                5,
+	KindExpired:            6,
+	KindInvalidCredentials: 7,
+	KindClockSkew:          8,
+	KindThrottled:          9,
+	KindNetwork:            10,
+	KindTimeout:            11,
+	KindCanceled:           130,
+}
+
+// Error is a failure that has been classified, along with a hint for how the
+// user might remedy it.
+type Error struct {
+	Kind Kind
+	Hint string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the process exit code for the given error.
+func ExitCode(err error) int {
+	var cerr *Error
+	if errors.As(err, &cerr) {
+		return exitCodes[cerr.Kind]
+	}
+	return exitCodes[KindUnknown]
+}
+
+// Hint returns a hint for remedying the given error, or an empty string if
+// there is none.
+func Hint(err error) string {
+	var cerr *Error
+	if errors.As(err, &cerr) {
+		return cerr.Hint
+	}
+	return ""
+}
+
+// classify determines what kind of failure the given error from an STS API
+// call is, and wraps it in an Error with an appropriate hint.
+func classify(err error) error {
+	var cerr *Error
+	if err == nil || errors.As(err, &cerr) {
+		return err
+	}
+
+	// Check if the API call or prompt was abandoned, either because the
+	// user interrupted it or because it took too long.
+	switch contextError(err) {
+	case context.DeadlineExceeded:
+		return &Error{Kind: KindTimeout, Hint: "try again with a longer --timeout", Err: err}
+	case context.Canceled:
+		return &Error{Kind: KindCanceled, Err: err}
+	}
+
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return err
+	}
+
+	switch code, message := aerr.Code(), aerr.Message(); {
+	case code == "AccessDenied" && strings.Contains(message, "MultiFactorAuthentication"):
+		return &Error{Kind: KindMFA, Hint: "MFA code rejected, wait for the next code and try again", Err: err}
+
+	case code == "AccessDenied":
+		return &Error{Kind: KindAccessDenied, Hint: "check that the role's trust policy allows the source credentials, and that any external id or MFA device matches", Err: err}
+
+	case code == "ExpiredToken" || code == "ExpiredTokenException" || code == "TokenRefreshRequired":
+		return &Error{Kind: KindExpired, Hint: "source credentials expired, refresh them and try again", Err: err}
+
+	case code == "RequestExpired" || strings.Contains(message, "Signature expired"):
+		return &Error{Kind: KindClockSkew, Hint: "check that the system clock is correct", Err: err}
+
+	case code == "InvalidClientTokenId" || code == "SignatureDoesNotMatch" || code == "UnrecognizedClientException":
+		return &Error{Kind: KindInvalidCredentials, Hint: "check that the access key is correct, and has not been deactivated", Err: err}
+
+	case code == "Throttling" || code == "ThrottlingException" || code == "RequestLimitExceeded":
+		return &Error{Kind: KindThrottled, Hint: "STS is throttling requests, try again later", Err: err}
+
+	case code == request.ErrCodeRequestError || code == request.ErrCodeResponseTimeout || isNetError(aerr.OrigErr()):
+		return &Error{Kind: KindNetwork, Hint: "check network connectivity, and the configured endpoint_url and region", Err: err}
+
+	default:
+		return err
+	}
+}
+
+// contextError returns the context.Context error that caused the given error,
+// if any. The AWS SDK wraps these errors, but without supporting errors.Is.
+func contextError(err error) error {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == request.CanceledErrorCode {
+		err = aerr.OrigErr()
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return context.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return context.Canceled
+	default:
+		return nil
+	}
+}
+
+// isNetError reports whether the given error is a network error.
+func isNetError(err error) bool {
+	var nerr net.Error
+	return err != nil && errors.As(err, &nerr)
+}
+
+// profileError classifies the given error from looking up the named profile,
+// suggesting a similarly named profile if it does not exist.
+func profileError(cfg *config.Config, profile string, err error) error {
+	if err != config.ErrUnknownProfile {
+		return &Error{Kind: KindConfig, Err: err}
+	}
+
+	var hint string
+	if suggestion := cfg.Suggest(profile); suggestion != "" {
+		hint = "did you mean profile " + suggestion + "?"
+	}
+
+	return &Error{Kind: KindUnknownProfile, Hint: hint, Err: err}
+}
@@ -0,0 +1,78 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err      error
+		exitCode int
+	}{
+		{
+			err:      errors.New("something went wrong"),
+			exitCode: 1,
+		},
+		{
+			err:      awserr.New("AccessDenied", "MultiFactorAuthentication failed with invalid MFA one time pass code.", nil),
+			exitCode: 5,
+		},
+		{
+			err:      awserr.New("AccessDenied", "User: arn:aws:iam::000000000000:user/alice is not authorized to perform: sts:AssumeRole", nil),
+			exitCode: 4,
+		},
+		{
+			err:      awserr.New("ExpiredToken", "The security token included in the request is expired", nil),
+			exitCode: 6,
+		},
+		{
+			err:      awserr.New("SignatureDoesNotMatch", "Signature expired: 20200101T000000Z is now earlier than 20200101T001000Z", nil),
+			exitCode: 8,
+		},
+		{
+			err:      awserr.New("InvalidClientTokenId", "The security token included in the request is invalid.", nil),
+			exitCode: 7,
+		},
+		{
+			err:      awserr.New("Throttling", "Rate exceeded", nil),
+			exitCode: 9,
+		},
+		{
+			err:      awserr.New("RequestError", "send request failed", nil),
+			exitCode: 10,
+		},
+		{
+			err:      awserr.New("RequestCanceled", "request context canceled", context.DeadlineExceeded),
+			exitCode: 11,
+		},
+		{
+			err:      context.Canceled,
+			exitCode: 130,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			err := chainError{
+				profile: "test",
+				err:     classify(test.err),
+			}
+
+			if code := ExitCode(err); code != test.exitCode {
+				t.Fatalf("expected exit code %d but got %d", test.exitCode, code)
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error to wrap %q", test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+)
+
+// Limits imposed by STS on API call parameters.
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_iam-quotas.html
+const (
+	maxChainedRoleDuration = time.Hour
+	maxPolicyARNs          = 10
+	maxPolicySize          = 2048
+	maxRoleDuration        = 12 * time.Hour
+	maxSessionDuration     = 36 * time.Hour
+	minDuration            = 15 * time.Minute
+)
+
+// sessionNamePattern matches a valid role session name or federated user
+// name, excluding length requirements.
+var sessionNamePattern = regexp.MustCompile(`^[\w+=,.@-]+$`)
+
+// credentialKind describes where a set of credentials came from, which
+// determines which STS API calls they are permitted to make.
+type credentialKind int
+
+const (
+	kindNone credentialKind = iota
+	kindUser
+	kindTemporary
+	kindSession
+	kindRole
+	kindFederated
+)
+
+// Step describes a single API call that a Transformer would make.
+type Step struct {
+	// Profile is the profile that the API call obtains credentials for.
+	Profile string
+
+	// Source is the profile whose credentials are used to make the API call.
+	Source string
+
+	// API is the name of the STS API call, like "sts:AssumeRole".
+	API string
+
+	// Details are the parameters that the API call would be made with.
+	Details []Detail
+
+	// Warnings are any known STS constraints that the API call would run
+	// afoul of.
+	Warnings []string
+}
+
+// Detail is a single labeled parameter of an API call.
+type Detail struct {
+	Label string
+	Value string
+}
+
+// add appends a labeled detail to the Step, if the value is not empty.
+func (s *Step) add(label, value string) {
+	if value != "" {
+		s.Details = append(s.Details, Detail{label, value})
+	}
+}
+
+// warn appends a warning to the Step.
+func (s *Step) warn(format string, args ...interface{}) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Explain describes each API call that the given transformers would make
+// when starting with the given sts.Credentials, without making any API calls
+// or prompting the user. Known STS constraints are included as warnings.
+func Explain(creds *sts.Credentials, transformers []Transformer) []Step {
+	// Determine what kind of initial credentials are being used.
+	kind := kindNone
+	switch {
+	case creds == nil:
+	case aws.StringValue(creds.SessionToken) != "":
+		kind = kindTemporary
+	default:
+		kind = kindUser
+	}
+
+	steps := make([]Step, 0, len(transformers))
+	for _, transformer := range transformers {
+		var step Step
+
+		switch transform := transformer.(type) {
+		case AssumeRoleTransform:
+			step = Step{
+				Profile: transform.Profile,
+				Source:  transform.Role.SourceProfile,
+				API:     "sts:AssumeRole",
+			}
+			step.add("role arn", transform.Role.RoleARN)
+			step.add("session name", valueOr(transform.Role.RoleSessionName, defaultRoleSessionName))
+			step.add("external id", transform.Role.ExternalID)
+			step.add("mfa device", transform.Role.MFASerial)
+			step.add("yubikey slot", transform.Role.YubikeySlot)
+			step.add("source identity", transform.Role.SourceIdentity)
+
+			duration := explainDuration(&step, transform.Role.DurationSeconds, maxRoleDuration)
+			explainPolicy(&step, transform.Role.Policy, transform.Role.PolicyARNs)
+			explainTags(&step, transform.Role.Tags, transform.Role.TransitiveTagKeys)
+			if err := config.ValidateSourceIdentity(transform.Role.SourceIdentity); err != nil {
+				step.warn("%v", err)
+			}
+			explainSessionName(&step, valueOr(transform.Role.RoleSessionName, defaultRoleSessionName))
+
+			// Assuming a role with the credentials of another role is role
+			// chaining, which only matters if a session longer than it
+			// allows was asked for.
+			switch {
+			case kind == kindFederated:
+				step.warn("federated user credentials can not be used to call sts:AssumeRole")
+			case kind != kindRole:
+			case transform.Role.DurationSeconds == config.MaxDuration:
+				step.warn("role chaining limits sessions to %s", maxChainedRoleDuration)
+			case duration > maxChainedRoleDuration:
+				step.warn("role chaining limits sessions to %s, but %s was requested", maxChainedRoleDuration, duration)
+			}
+			kind = kindRole
+
+		case SessionTokenTransform:
+			step = Step{
+				Profile: transform.Profile,
+				Source:  transform.Session.SourceProfile,
+				API:     "sts:GetSessionToken",
+			}
+			step.add("mfa device", transform.Session.MFASerial)
+			step.add("yubikey slot", transform.Session.YubikeySlot)
+
+			explainDuration(&step, transform.Session.DurationSeconds, maxSessionDuration)
+
+			if kind != kindUser {
+				step.warn("sts:GetSessionToken must be called with long-term IAM user credentials")
+			}
+			kind = kindSession
+
+		case FederationTokenTransform:
+			step = Step{
+				Profile: transform.Profile,
+				Source:  transform.Federate.SourceProfile,
+				API:     "sts:GetFederationToken",
+			}
+			step.add("name", valueOr(transform.Federate.Name, defaultFederationName))
+
+			explainDuration(&step, transform.Federate.DurationSeconds, maxSessionDuration)
+			explainPolicy(&step, transform.Federate.Policy, transform.Federate.PolicyARNs)
+			explainTags(&step, transform.Federate.Tags, nil)
+			explainName(&step, "name", valueOr(transform.Federate.Name, defaultFederationName), 32)
+
+			if kind != kindUser {
+				step.warn("sts:GetFederationToken must be called with long-term IAM user credentials")
+			}
+			kind = kindFederated
+
+		case SAMLTransform:
+			step = Step{
+				Profile: transform.Profile,
+				API:     "sts:AssumeRoleWithSAML",
+			}
+			step.add("role arn", valueOr(transform.SAML.RoleARN, "(chosen from assertion)"))
+			step.add("principal arn", transform.SAML.PrincipalARN)
+			switch {
+			case transform.SAML.AssertionFile != "":
+				step.add("assertion file", transform.SAML.AssertionFile)
+			case transform.SAML.AssertionCommand != "":
+				step.add("assertion command", transform.SAML.AssertionCommand)
+			default:
+				step.add("idp url", transform.SAML.IdPURL)
+			}
+
+			explainDuration(&step, transform.SAML.DurationSeconds, maxRoleDuration)
+			explainPolicy(&step, transform.SAML.Policy, transform.SAML.PolicyARNs)
+			kind = kindRole
+
+		default:
+			step = Step{
+				API: fmt.Sprintf("%T", transformer),
+			}
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps
+}
+
+// explainDuration adds the requested session duration to the Step, warning
+// if it is outside of the allowed range. The effective duration is returned.
+func explainDuration(step *Step, seconds int, max time.Duration) time.Duration {
+	duration := time.Duration(seconds) * time.Second
+	switch seconds {
+	case 0:
+		duration = defaultDuration
+	case config.MaxDuration:
+		// The longest duration the role allows is negotiated when the API
+		// call is made.
+		step.add("duration", fmt.Sprintf("max (up to %s)", max))
+		return max
+	}
+
+	step.add("duration", fmt.Sprintf("%s (%ds)", duration, int(duration.Seconds())))
+
+	if duration < minDuration || duration > max {
+		step.warn("duration must be between %s and %s", minDuration, max)
+	}
+
+	return duration
+}
+
+// explainPolicy adds the size of the session policy and number of policy ARNs
+// to the Step, warning if either exceeds STS limits.
+func explainPolicy(step *Step, policy string, policyARNs []string) {
+	if policy != "" {
+		step.add("session policy", fmt.Sprintf("%d bytes", len(policy)))
+		if len(policy) > maxPolicySize {
+			step.warn("session policy exceeds %d bytes, and may be rejected", maxPolicySize)
+		}
+	}
+
+	for _, policyARN := range policyARNs {
+		step.add("policy arn", policyARN)
+	}
+	if len(policyARNs) > maxPolicyARNs {
+		step.warn("at most %d policy arns can be given, but %d were", maxPolicyARNs, len(policyARNs))
+	}
+}
+
+// explainTags adds the session tags and transitive tag keys to the Step,
+// warning if they do not satisfy STS requirements.
+func explainTags(step *Step, tags []config.Tag, transitiveTagKeys []string) {
+	for _, tag := range tags {
+		step.add("session tag", tag.Key+"="+tag.Value)
+	}
+	for _, key := range transitiveTagKeys {
+		step.add("transitive tag key", key)
+	}
+	if err := config.ValidateTags(tags, transitiveTagKeys); err != nil {
+		step.warn("%v", err)
+	}
+}
+
+// explainSessionName warns if the given role session name does not satisfy
+// STS requirements. Templates are checked by rendering them with placeholder
+// values, since their real values are only known when the chain is run.
+func explainSessionName(step *Step, name string) {
+	if !strings.Contains(name, "{{") {
+		explainName(step, "session name", name, maxSessionNameLength)
+		return
+	}
+
+	tmpl, err := template.New("role_session_name").Option("missingkey=error").Parse(name)
+	if err == nil {
+		err = tmpl.Execute(ioutil.Discard, sessionNamePlaceholders{})
+	}
+	if err != nil {
+		step.warn("session name template is invalid: %v", err)
+	}
+}
+
+// sessionNamePlaceholders mirrors the fields and methods of sessionNameData,
+// without making any API calls.
+type sessionNamePlaceholders struct {
+	User     string
+	Hostname string
+	Time     string
+}
+
+// CallerName returns an empty placeholder.
+func (sessionNamePlaceholders) CallerName() string {
+	return ""
+}
+
+// explainName warns if the given name does not satisfy STS requirements.
+func explainName(step *Step, label, name string, max int) {
+	if len(name) < 2 || len(name) > max || !sessionNamePattern.MatchString(name) {
+		step.warn("%s must be 2-%d characters from [A-Za-z0-9+=,.@_-]", label, max)
+	}
+}
+
+// valueOr returns the given value, or the fallback if it is empty.
+func valueOr(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,115 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+)
+
+func TestExplainWarnings(t *testing.T) {
+	user := &sts.Credentials{
+		AccessKeyId:     aws.String("AKIA"),
+		SecretAccessKey: aws.String("secret"),
+	}
+
+	temporary := &sts.Credentials{
+		AccessKeyId:     aws.String("ASIA"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+	}
+
+	role := AssumeRoleTransform{
+		Role: &config.Role{
+			DurationSeconds: 3600,
+			RoleARN:         "arn:aws:iam::000000000000:role/admin",
+		},
+	}
+
+	longRole := AssumeRoleTransform{
+		Role: &config.Role{
+			DurationSeconds: 7200,
+			RoleARN:         "arn:aws:iam::000000000000:role/admin",
+		},
+	}
+
+	maxRole := AssumeRoleTransform{
+		Role: &config.Role{
+			DurationSeconds: config.MaxDuration,
+			RoleARN:         "arn:aws:iam::000000000000:role/admin",
+		},
+	}
+
+	session := SessionTokenTransform{
+		Session: &config.Session{
+			DurationSeconds: 3600,
+		},
+	}
+
+	tests := []struct {
+		creds        *sts.Credentials
+		transformers []Transformer
+		warnings     [][]string
+	}{
+		{
+			creds:        user,
+			transformers: []Transformer{session, role},
+			warnings:     [][]string{nil, nil},
+		},
+		{
+			creds:        user,
+			transformers: []Transformer{role, longRole},
+			warnings: [][]string{
+				nil,
+				{"role chaining limits sessions to 1h0m0s, but 2h0m0s was requested"},
+			},
+		},
+		{
+			creds:        user,
+			transformers: []Transformer{role, role},
+			warnings:     [][]string{nil, nil},
+		},
+		{
+			creds:        user,
+			transformers: []Transformer{role, maxRole},
+			warnings: [][]string{
+				nil,
+				{"role chaining limits sessions to 1h0m0s"},
+			},
+		},
+		{
+			creds:        temporary,
+			transformers: []Transformer{longRole},
+			warnings:     [][]string{nil},
+		},
+		{
+			creds:        temporary,
+			transformers: []Transformer{session},
+			warnings: [][]string{
+				{"sts:GetSessionToken must be called with long-term IAM user credentials"},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			steps := Explain(test.creds, test.transformers)
+
+			var warnings [][]string
+			for _, step := range steps {
+				warnings = append(warnings, step.Warnings)
+			}
+
+			if !reflect.DeepEqual(warnings, test.warnings) {
+				t.Fatalf("expected warnings %q but got %q", test.warnings, warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/fakests"
+)
+
+func TestFakeSTS(t *testing.T) {
+	server := httptest.NewServer(fakests.New(fakests.Config{
+		Users: []fakests.User{
+			{
+				ARN:             "arn:aws:iam::111111111111:user/alice",
+				AccessKeyID:     "AKIAALICE",
+				SecretAccessKey: "secret",
+			},
+		},
+		Roles: []fakests.Role{
+			{
+				ARN:                "arn:aws:iam::222222222222:role/admin",
+				Trust:              []string{"arn:aws:iam::111111111111:root"},
+				MaxSessionDuration: 7200,
+			},
+			{
+				ARN:        "arn:aws:iam::333333333333:role/deploy",
+				Trust:      []string{"arn:aws:iam::222222222222:role/admin"},
+				ExternalID: "xyz",
+			},
+		},
+	}))
+	defer server.Close()
+
+	endpoint := config.Endpoint{
+		EndpointURL: server.URL,
+		Region:      "us-east-1",
+	}
+
+	alice := &sts.Credentials{
+		AccessKeyId:     aws.String("AKIAALICE"),
+		SecretAccessKey: aws.String("secret"),
+	}
+
+	admin := AssumeRoleTransform{"admin", &config.Role{
+		Endpoint: endpoint,
+		RoleARN:  "arn:aws:iam::222222222222:role/admin",
+	}}
+
+	tests := []struct {
+		creds      *sts.Credentials
+		transforms []Transformer
+		arn        string
+		exitCode   int
+	}{
+		{
+			creds:      alice,
+			transforms: []Transformer{admin},
+			arn:        "arn:aws:sts::222222222222:assumed-role/admin/Temp",
+		},
+		{
+			creds: alice,
+			transforms: []Transformer{
+				AssumeRoleTransform{"admin", &config.Role{
+					DurationSeconds: config.MaxDuration,
+					Endpoint:        endpoint,
+					RoleARN:         "arn:aws:iam::222222222222:role/admin",
+					RoleSessionName: "alice",
+				}},
+			},
+			arn: "arn:aws:sts::222222222222:assumed-role/admin/alice",
+		},
+		{
+			creds: alice,
+			transforms: []Transformer{
+				admin,
+				AssumeRoleTransform{"deploy", &config.Role{
+					Endpoint:   endpoint,
+					ExternalID: "xyz",
+					RoleARN:    "arn:aws:iam::333333333333:role/deploy",
+				}},
+			},
+			arn: "arn:aws:sts::333333333333:assumed-role/deploy/Temp",
+		},
+		{
+			creds: alice,
+			transforms: []Transformer{
+				admin,
+				AssumeRoleTransform{"deploy", &config.Role{
+					Endpoint:   endpoint,
+					ExternalID: "abc",
+					RoleARN:    "arn:aws:iam::333333333333:role/deploy",
+				}},
+			},
+			exitCode: 4,
+		},
+		{
+			creds: alice,
+			transforms: []Transformer{
+				AssumeRoleTransform{"deploy", &config.Role{
+					Endpoint:   endpoint,
+					ExternalID: "xyz",
+					RoleARN:    "arn:aws:iam::333333333333:role/deploy",
+				}},
+			},
+			exitCode: 4,
+		},
+		{
+			creds: alice,
+			transforms: []Transformer{
+				FederationTokenTransform{"federated", &config.Federate{
+					Endpoint: endpoint,
+					Name:     "alice",
+				}},
+			},
+			arn: "arn:aws:sts::111111111111:federated-user/alice",
+		},
+		{
+			creds: &sts.Credentials{
+				AccessKeyId:     aws.String("AKIAALICE"),
+				SecretAccessKey: aws.String("wrong"),
+			},
+			transforms: []Transformer{admin},
+			exitCode:   7,
+		},
+		{
+			creds: &sts.Credentials{
+				AccessKeyId:     aws.String("AKIABOB"),
+				SecretAccessKey: aws.String("secret"),
+			},
+			transforms: []Transformer{admin},
+			exitCode:   7,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			results := Resolve(context.Background(), []Target{
+				{
+					Creds:      test.creds,
+					Transforms: test.transforms,
+					Endpoint:   endpoint,
+				},
+			})
+
+			switch err := results[0].Err; {
+			case err != nil && test.exitCode == 0:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.exitCode != 0:
+				t.Fatalf("expected an error but got no error")
+			case err != nil:
+				if code := ExitCode(err); code != test.exitCode {
+					t.Fatalf("expected exit code %d but got %d for error %q", test.exitCode, code, err)
+				}
+				return
+			}
+
+			if arn := results[0].Identity.ARN; arn != test.arn {
+				t.Fatalf("expected arn %q but got %q", test.arn, arn)
+			}
+		})
+	}
+}
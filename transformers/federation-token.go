@@ -5,14 +5,15 @@
 package transformers
 
 import (
+	"context"
+
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/joshdk/aws-auth/config"
 )
 
 type FederationTokenTransform struct {
+	Profile  string
 	Federate *config.Federate
 }
 
@@ -21,7 +22,7 @@ const defaultFederationName = "Federated"
 // Transform takes the input sts.Credentials and the internal config.Federate
 // and performs a GetFederationToken. The sts.Credentials for the federated
 // session are returned.
-func (s FederationTokenTransform) Transform(creds *sts.Credentials) (*sts.Credentials, error) {
+func (s FederationTokenTransform) Transform(ctx context.Context, creds *sts.Credentials) (*sts.Credentials, error) {
 	// Pack the input struct with appropriate data. Fields that have a
 	// zero-value must be nil (opposed if a pointer to a zero-value).
 	input := sts.GetFederationTokenInput{}
@@ -48,21 +49,17 @@ func (s FederationTokenTransform) Transform(creds *sts.Credentials) (*sts.Creden
 		input.Name = aws.String(defaultFederationName)
 	}
 
-	// Create a session with the input credentials that will be used in the
+	input.Tags = tags(s.Federate.Tags)
+
+	// Create a client with the input credentials that will be used in the
 	// following API call.
-	sess, err := session.NewSession(&aws.Config{
-		Credentials: credentials.NewStaticCredentials(
-			aws.StringValue(creds.AccessKeyId),
-			aws.StringValue(creds.SecretAccessKey),
-			aws.StringValue(creds.SessionToken),
-		),
-	})
+	client, err := newClient(ctx, creds, s.Federate.Endpoint)
 	if err != nil {
 		return nil, err
 	}
 
 	// Perform the actual API call.
-	result, err := sts.New(sess).GetFederationToken(&input)
+	result, err := client.GetFederationTokenWithContext(ctx, &input)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,34 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+// Interactive returns a reason why making the given transforms would require
+// the user to interact, like entering an MFA code or completing a browser
+// login. An empty string is returned if no interaction is needed.
+func Interactive(transformers []Transformer) string {
+	for _, transformer := range transformers {
+		switch transform := transformer.(type) {
+		case AssumeRoleTransform:
+			if transform.Role.MFASerial != "" {
+				return "profile " + transform.Profile + " requires MFA"
+			}
+
+		case SessionTokenTransform:
+			if transform.Session.MFASerial != "" {
+				return "profile " + transform.Profile + " requires MFA"
+			}
+
+		case SAMLTransform:
+			if transform.SAML.AssertionFile == "" && transform.SAML.AssertionCommand == "" {
+				return "profile " + transform.Profile + " requires a browser login"
+			}
+			if transform.SAML.RoleARN == "" {
+				return "profile " + transform.Profile + " may require choosing a role"
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,89 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/joshdk/aws-auth/config"
+)
+
+func TestInteractive(t *testing.T) {
+	tests := []struct {
+		transforms []Transformer
+		reason     string
+	}{
+		{},
+		{
+			transforms: []Transformer{
+				AssumeRoleTransform{"admin", &config.Role{
+					RoleARN: "arn:aws:iam::000000000000:role/admin",
+				}},
+				FederationTokenTransform{"federated", &config.Federate{}},
+			},
+		},
+		{
+			transforms: []Transformer{
+				AssumeRoleTransform{"admin", &config.Role{
+					RoleARN: "arn:aws:iam::000000000000:role/admin",
+				}},
+				AssumeRoleTransform{"deploy", &config.Role{
+					MFASerial: "arn:aws:iam::000000000000:mfa/alice",
+					RoleARN:   "arn:aws:iam::000000000000:role/deploy",
+				}},
+			},
+			reason: "profile deploy requires MFA",
+		},
+		{
+			transforms: []Transformer{
+				SessionTokenTransform{"session", &config.Session{
+					MFASerial: "arn:aws:iam::000000000000:mfa/alice",
+				}},
+			},
+			reason: "profile session requires MFA",
+		},
+		{
+			transforms: []Transformer{
+				SessionTokenTransform{"session", &config.Session{}},
+			},
+		},
+		{
+			transforms: []Transformer{
+				SAMLTransform{"saml", &config.SAML{
+					IdPURL:       "https://idp.example.com/login",
+					PrincipalARN: "arn:aws:iam::000000000000:saml-provider/idp",
+					RoleARN:      "arn:aws:iam::000000000000:role/admin",
+				}},
+			},
+			reason: "profile saml requires a browser login",
+		},
+		{
+			transforms: []Transformer{
+				SAMLTransform{"saml", &config.SAML{
+					AssertionFile: "assertion.xml",
+				}},
+			},
+			reason: "profile saml may require choosing a role",
+		},
+		{
+			transforms: []Transformer{
+				SAMLTransform{"saml", &config.SAML{
+					AssertionCommand: "get-assertion",
+					PrincipalARN:     "arn:aws:iam::000000000000:saml-provider/idp",
+					RoleARN:          "arn:aws:iam::000000000000:role/admin",
+				}},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			if actual := Interactive(test.transforms); actual != test.reason {
+				t.Fatalf("expected reason %q but got %q", test.reason, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// noticeKey is the context.Context key for the notice writer.
+type noticeKey struct{}
+
+// WithNotices returns a copy of the given context.Context, such that notices
+// from any transforms made with it, like a session duration being shortened,
+// are written to the given writer. Notices are discarded otherwise.
+func WithNotices(ctx context.Context, out io.Writer) context.Context {
+	return context.WithValue(ctx, noticeKey{}, out)
+}
+
+// notice writes a line to the notice writer carried by the given
+// context.Context, if there is one.
+func notice(ctx context.Context, format string, args ...interface{}) {
+	if out, ok := ctx.Value(noticeKey{}).(io.Writer); ok {
+		fmt.Fprintf(out, "aws-auth: "+format+"\n", args...)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+)
+
+// Target is a chain of transforms for obtaining credentials for a single
+// profile, as found by Chain.
+type Target struct {
+	// Profile is the name of the profile that credentials are obtained for.
+	Profile string
+
+	// Creds are the initial credentials passed to the first transform.
+	Creds *sts.Credentials
+
+	// Transforms are the chain of transforms to make.
+	Transforms []Transformer
+
+	// Endpoint is used for enriching the final credentials.
+	Endpoint config.Endpoint
+}
+
+// Result is the outcome of resolving a single Target.
+type Result struct {
+	Profile  string
+	Identity *Identity
+	Err      error
+}
+
+// Resolve obtains credentials for each of the given targets, and enriches
+// them with identity information. Results are returned in the same order as
+// the targets.
+//
+// Targets often share a common prefix, like a session with an MFA prompt
+// that several roles are assumed from. Each distinct step is made only once,
+// and its result is shared by every target that includes it. Steps that are
+// not shared are made concurrently.
+func Resolve(ctx context.Context, targets []Target) []Result {
+	memo := newMemo()
+	results := make([]Result, len(targets))
+
+	var wg sync.WaitGroup
+	for index, target := range targets {
+		wg.Add(1)
+		go func(index int, target Target) {
+			defer wg.Done()
+
+			results[index] = Result{
+				Profile: target.Profile,
+			}
+
+			// Make the transforms, reusing any shared steps.
+			creds, err := memo.transform(ctx, target)
+			if err != nil {
+				results[index].Err = err
+				return
+			}
+
+			// Enrich credentials with identity information.
+			results[index].Identity, results[index].Err = Enrich(ctx, creds, target.Endpoint)
+		}(index, target)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// memo records the outcome of each step that has been made, keyed by the
+// step itself and every step that preceded it.
+type memo struct {
+	lock  sync.Mutex
+	steps map[string]*step
+}
+
+// step is the outcome of a single transform. The once guards against the
+// transform being made more than once.
+type step struct {
+	once  sync.Once
+	creds *sts.Credentials
+	err   error
+}
+
+func newMemo() *memo {
+	return &memo{
+		steps: make(map[string]*step),
+	}
+}
+
+// lookup returns the step for the given key, creating it if it does not yet
+// exist.
+func (m *memo) lookup(key string) *step {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if s, found := m.steps[key]; found {
+		return s
+	}
+
+	s := &step{}
+	m.steps[key] = s
+	return s
+}
+
+// transform makes each transform in the given target, reusing the result of
+// any steps that another target already made.
+func (m *memo) transform(ctx context.Context, target Target) (*sts.Credentials, error) {
+	// Steps starting from different initial credentials are never the same.
+	var key string
+	if target.Creds != nil {
+		key = aws.StringValue(target.Creds.AccessKeyId)
+	}
+
+	creds := target.Creds
+	for _, transformer := range target.Transforms {
+		// Two steps are the same if they have identical configuration, and
+		// are preceded by identical steps.
+		body, err := json.Marshal(transformer)
+		if err != nil {
+			return nil, err
+		}
+		key += "\n" + string(body)
+
+		s := m.lookup(key)
+		s.once.Do(func() {
+			s.creds, s.err = Transform(ctx, creds, []Transformer{transformer})
+		})
+		if s.err != nil {
+			return nil, s.err
+		}
+
+		creds = s.creds
+	}
+
+	return creds, nil
+}
@@ -0,0 +1,211 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+	"github.com/joshdk/aws-auth/fakests"
+	"github.com/joshdk/aws-auth/mfa"
+)
+
+// countingTransform appends its name to the access key id of the given
+// credentials, and counts how many times it was called.
+type countingTransform struct {
+	Name  string
+	calls *int32
+}
+
+func (c countingTransform) Transform(_ context.Context, creds *sts.Credentials) (*sts.Credentials, error) {
+	atomic.AddInt32(c.calls, 1)
+	return &sts.Credentials{
+		AccessKeyId: aws.String(aws.StringValue(creds.AccessKeyId) + "/" + c.Name),
+	}, nil
+}
+
+func TestMemoTransform(t *testing.T) {
+	var sessionCalls, adminCalls, readonlyCalls int32
+	session := countingTransform{"session", &sessionCalls}
+	admin := countingTransform{"admin", &adminCalls}
+	readonly := countingTransform{"readonly", &readonlyCalls}
+
+	user := &sts.Credentials{AccessKeyId: aws.String("user")}
+	other := &sts.Credentials{AccessKeyId: aws.String("other")}
+
+	targets := []Target{
+		{Creds: user, Transforms: []Transformer{session, admin}},
+		{Creds: user, Transforms: []Transformer{session, readonly}},
+		{Creds: user, Transforms: []Transformer{session}},
+		{Creds: other, Transforms: []Transformer{session, admin}},
+	}
+	expected := []string{
+		"user/session/admin",
+		"user/session/readonly",
+		"user/session",
+		"other/session/admin",
+	}
+
+	memo := newMemo()
+	actual := make([]string, len(targets))
+
+	var wg sync.WaitGroup
+	for index, target := range targets {
+		wg.Add(1)
+		go func(index int, target Target) {
+			defer wg.Done()
+			creds, err := memo.transform(context.Background(), target)
+			if err != nil {
+				t.Errorf("expected no error but got error %q", err)
+				return
+			}
+			actual[index] = aws.StringValue(creds.AccessKeyId)
+		}(index, target)
+	}
+	wg.Wait()
+
+	for index := range expected {
+		if actual[index] != expected[index] {
+			t.Fatalf("expected credentials %s but got %s", expected[index], actual[index])
+		}
+	}
+
+	if sessionCalls != 2 || adminCalls != 2 || readonlyCalls != 1 {
+		t.Fatalf("expected 2, 2, and 1 calls but got %d, %d, and %d", sessionCalls, adminCalls, readonlyCalls)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	server := httptest.NewServer(fakests.New(fakests.Config{
+		Users: []fakests.User{
+			{
+				ARN:             "arn:aws:iam::111111111111:user/alice",
+				AccessKeyID:     "AKIAALICE",
+				SecretAccessKey: "secret",
+				MFASerial:       "arn:aws:iam::111111111111:mfa/alice",
+				MFACode:         "123456",
+			},
+		},
+		Roles: []fakests.Role{
+			{
+				ARN:        "arn:aws:iam::222222222222:role/admin",
+				Trust:      []string{"arn:aws:iam::111111111111:root"},
+				RequireMFA: true,
+			},
+			{
+				ARN:        "arn:aws:iam::222222222222:role/readonly",
+				Trust:      []string{"arn:aws:iam::111111111111:root"},
+				RequireMFA: true,
+			},
+		},
+	}))
+	defer server.Close()
+
+	endpoint := config.Endpoint{
+		EndpointURL: server.URL,
+		Region:      "us-east-1",
+	}
+
+	alice := &sts.Credentials{
+		AccessKeyId:     aws.String("AKIAALICE"),
+		SecretAccessKey: aws.String("secret"),
+	}
+
+	// Every target shares the same session step, which prompts for MFA.
+	session := SessionTokenTransform{"session", &config.Session{
+		DurationSeconds: 3600,
+		Endpoint:        endpoint,
+		MFASerial:       "arn:aws:iam::111111111111:mfa/alice",
+	}}
+	role := func(name string) AssumeRoleTransform {
+		return AssumeRoleTransform{name, &config.Role{
+			Endpoint: endpoint,
+			RoleARN:  "arn:aws:iam::222222222222:role/" + name,
+		}}
+	}
+
+	targets := []Target{
+		{Profile: "admin", Creds: alice, Transforms: []Transformer{session, role("admin")}, Endpoint: endpoint},
+		{Profile: "session", Creds: alice, Transforms: []Transformer{session}, Endpoint: endpoint},
+		{Profile: "missing", Creds: alice, Transforms: []Transformer{session, role("missing")}, Endpoint: endpoint},
+		{Profile: "readonly", Creds: alice, Transforms: []Transformer{session, role("readonly")}, Endpoint: endpoint},
+	}
+
+	type outcome struct {
+		arn      string
+		exitCode int
+	}
+
+	tests := []struct {
+		code     string
+		outcomes []outcome
+	}{
+		{
+			code: "123456",
+			outcomes: []outcome{
+				{arn: "arn:aws:sts::222222222222:assumed-role/admin/Temp"},
+				{arn: "arn:aws:iam::111111111111:user/alice"},
+				{exitCode: 4},
+				{arn: "arn:aws:sts::222222222222:assumed-role/readonly/Temp"},
+			},
+		},
+		{
+			code: "000000",
+			outcomes: []outcome{
+				{exitCode: 5},
+				{exitCode: 5},
+				{exitCode: 5},
+				{exitCode: 5},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			var prompts int32
+			ctx := mfa.WithPrompter(context.Background(), func(context.Context, string) (string, error) {
+				atomic.AddInt32(&prompts, 1)
+				return test.code, nil
+			})
+
+			results := Resolve(ctx, targets)
+
+			// The shared session step prompts for MFA once, however it ends.
+			if prompts != 1 {
+				t.Fatalf("expected 1 mfa prompt but got %d", prompts)
+			}
+
+			for index, result := range results {
+				expected := test.outcomes[index]
+				if result.Profile != targets[index].Profile {
+					t.Fatalf("expected result for profile %s but got %s", targets[index].Profile, result.Profile)
+				}
+
+				switch err := result.Err; {
+				case err != nil && expected.exitCode == 0:
+					t.Fatalf("expected no error for profile %s but got error %q", result.Profile, err)
+				case err == nil && expected.exitCode != 0:
+					t.Fatalf("expected an error for profile %s but got no error", result.Profile)
+				case err != nil:
+					if code := ExitCode(err); code != expected.exitCode {
+						t.Fatalf("expected exit code %d for profile %s but got %d for error %q", expected.exitCode, result.Profile, code, err)
+					}
+					continue
+				}
+
+				if arn := result.Identity.ARN; arn != expected.arn {
+					t.Fatalf("expected arn %q for profile %s but got %q", expected.arn, result.Profile, arn)
+				}
+			}
+		})
+	}
+}
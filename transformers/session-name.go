@@ -0,0 +1,127 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+)
+
+// maxSessionNameLength is the maximum length of a role session name.
+const maxSessionNameLength = 64
+
+// sessionNameTimeFormat is the layout used for {{.Time}}. It only contains
+// characters that are allowed in a role session name.
+const sessionNameTimeFormat = "20060102T150405Z"
+
+// invalidSessionNamePattern matches runs of characters that are not allowed
+// in a role session name.
+var invalidSessionNamePattern = regexp.MustCompile(`[^\w+=,.@-]+`)
+
+// sessionNameData is the data available to a role_session_name template.
+type sessionNameData struct {
+	// User is the name of the local user running aws-auth.
+	User string
+
+	// Hostname is the name of the local machine.
+	Hostname string
+
+	// Time is the current UTC time, like "20060102T150405Z".
+	Time string
+
+	ctx      context.Context
+	creds    *sts.Credentials
+	endpoint config.Endpoint
+}
+
+// CallerName is the name of the principal that owns the source credentials,
+// as reported by GetCallerIdentity. The API call is only made if a template
+// uses {{.CallerName}}.
+// "arn:aws:iam::000000000000:user/alice" → "alice"
+// "arn:aws:sts::000000000000:assumed-role/admin/alice" → "alice"
+func (d sessionNameData) CallerName() (string, error) {
+	client, err := newClient(d.ctx, d.creds, d.endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := client.GetCallerIdentityWithContext(d.ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+
+	arn := aws.StringValue(output.Arn)
+	return arn[strings.LastIndexAny(arn, ":/")+1:], nil
+}
+
+// sessionName renders the given role_session_name template, and sanitizes the
+// result so that it satisfies STS requirements. Names that are not templates
+// are returned unchanged.
+// "{{.User}}-{{.Hostname}}" → "alice-laptop"
+func sessionName(ctx context.Context, name string, creds *sts.Credentials, endpoint config.Endpoint) (string, error) {
+	if !strings.Contains(name, "{{") {
+		return name, nil
+	}
+
+	data := sessionNameData{
+		Time:     time.Now().UTC().Format(sessionNameTimeFormat),
+		ctx:      ctx,
+		creds:    creds,
+		endpoint: endpoint,
+	}
+
+	// The local user and hostname are best effort, and are left empty if
+	// they can't be determined.
+	if current, err := user.Current(); err == nil {
+		data.User = current.Username
+	}
+	data.Hostname, _ = os.Hostname()
+
+	return renderSessionName(name, data)
+}
+
+// renderSessionName renders the given role_session_name template with the
+// given data, and sanitizes the result.
+func renderSessionName(name string, data sessionNameData) (string, error) {
+	tmpl, err := template.New("role_session_name").Option("missingkey=error").Parse(name)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", err
+	}
+
+	return sanitizeSessionName(builder.String()), nil
+}
+
+// sanitizeSessionName replaces any characters that are not allowed in a role
+// session name, and truncates it to the maximum allowed length. The default
+// session name is used if nothing usable remains.
+// `CORP\alice@laptop.local` → "CORP-alice@laptop.local"
+func sanitizeSessionName(name string) string {
+	name = invalidSessionNamePattern.ReplaceAllString(name, "-")
+	name = strings.Trim(name, "-")
+
+	if len(name) > maxSessionNameLength {
+		name = strings.TrimRight(name[:maxSessionNameLength], "-")
+	}
+
+	if len(name) < 2 {
+		return defaultRoleSessionName
+	}
+
+	return name
+}
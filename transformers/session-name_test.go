@@ -0,0 +1,68 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRenderSessionName(t *testing.T) {
+	data := sessionNameData{
+		User:     `CORP\alice`,
+		Hostname: "laptop.local",
+		Time:     "20200101T000000Z",
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "{{.User}}-{{.Hostname}}-{{.Time}}",
+			expected: "CORP-alice-laptop.local-20200101T000000Z",
+		},
+		{
+			name:     "deploy {{.User}}!",
+			expected: "deploy-CORP-alice",
+		},
+		{
+			name:     "{{.Hostname}}" + strings.Repeat("x", 80),
+			expected: "laptop.local" + strings.Repeat("x", 52),
+		},
+		{
+			name: "{{.Missing}}",
+			err:  true,
+		},
+		{
+			name: "{{.User",
+			err:  true,
+		},
+		{
+			name:     "{{if false}}x{{end}}",
+			expected: defaultRoleSessionName,
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			actual, err := renderSessionName(test.name, data)
+			switch {
+			case err != nil && test.err:
+				return
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			}
+
+			if actual != test.expected {
+				t.Fatalf("expected session name %q but got %q", test.expected, actual)
+			}
+		})
+	}
+}
@@ -5,22 +5,23 @@
 package transformers
 
 import (
+	"context"
+
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/joshdk/aws-auth/config"
 	"github.com/joshdk/aws-auth/mfa"
 )
 
 type SessionTokenTransform struct {
+	Profile string
 	Session *config.Session
 }
 
 // Transform takes the input sts.Credentials and the internal config.Session
 // and performs a GetSessionToken. The sts.Credentials for the session are
 // returned.
-func (s SessionTokenTransform) Transform(creds *sts.Credentials) (*sts.Credentials, error) {
+func (s SessionTokenTransform) Transform(ctx context.Context, creds *sts.Credentials) (*sts.Credentials, error) {
 	// Pack the input struct with appropriate data. Fields that have a
 	// zero-value must be nil (opposed if a pointer to a zero-value).
 	input := sts.GetSessionTokenInput{}
@@ -37,7 +38,7 @@ func (s SessionTokenTransform) Transform(creds *sts.Credentials) (*sts.Credentia
 
 	if s.Session.MFASerial != "" {
 		// Prompt the user to enter an MFA code.
-		code, err := mfa.Prompt(s.Session.MFASerial, s.Session.MFAMessage, s.Session.YubikeySlot)
+		code, err := mfa.Prompt(ctx, s.Session.MFASerial, s.Session.MFAMessage, s.Session.YubikeySlot)
 		if err != nil {
 			return nil, err
 		}
@@ -45,21 +46,15 @@ func (s SessionTokenTransform) Transform(creds *sts.Credentials) (*sts.Credentia
 		input.TokenCode = aws.String(code)
 	}
 
-	// Create a session with the input credentials that will be used in the
+	// Create a client with the input credentials that will be used in the
 	// following API call.
-	sess, err := session.NewSession(&aws.Config{
-		Credentials: credentials.NewStaticCredentials(
-			aws.StringValue(creds.AccessKeyId),
-			aws.StringValue(creds.SecretAccessKey),
-			aws.StringValue(creds.SessionToken),
-		),
-	})
+	client, err := newClient(ctx, creds, s.Session.Endpoint)
 	if err != nil {
 		return nil, err
 	}
 
 	// Perform the actual API call.
-	result, err := sts.New(sess).GetSessionToken(&input)
+	result, err := client.GetSessionTokenWithContext(ctx, &input)
 	if err != nil {
 		return nil, err
 	}
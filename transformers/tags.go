@@ -0,0 +1,87 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+)
+
+// tags converts the given list of config.Tag into the form used by STS.
+func tags(tags []config.Tag) []*sts.Tag {
+	var result []*sts.Tag
+	for _, tag := range tags {
+		result = append(result, &sts.Tag{
+			Key:   aws.String(tag.Key),
+			Value: aws.String(tag.Value),
+		})
+	}
+	return result
+}
+
+// WithTags adds the given session tags to the final transform in the chain,
+// which must either assume a role or get a federation token. Tags given here
+// replace any configured tags with the same key. The combined tags are
+// validated here, as configured tags are when the profile is parsed, so that
+// the transforms themselves never need to.
+func WithTags(chain []Transformer, extra []config.Tag) ([]Transformer, error) {
+	if len(extra) == 0 {
+		return chain, nil
+	}
+
+	// Copy the chain, so that the caller's transforms are left untouched.
+	chain = append([]Transformer{}, chain...)
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("session tags require a profile that assumes a role or gets a federation token")
+	}
+
+	switch transform := chain[len(chain)-1].(type) {
+	case AssumeRoleTransform:
+		role := *transform.Role
+		role.Tags = mergeTags(role.Tags, extra)
+		if err := config.ValidateTags(role.Tags, role.TransitiveTagKeys); err != nil {
+			return nil, &Error{Kind: KindConfig, Err: err}
+		}
+		transform.Role = &role
+		chain[len(chain)-1] = transform
+
+	case FederationTokenTransform:
+		federate := *transform.Federate
+		federate.Tags = mergeTags(federate.Tags, extra)
+		if err := config.ValidateTags(federate.Tags, nil); err != nil {
+			return nil, &Error{Kind: KindConfig, Err: err}
+		}
+		transform.Federate = &federate
+		chain[len(chain)-1] = transform
+
+	default:
+		return nil, fmt.Errorf("session tags require a profile that assumes a role or gets a federation token")
+	}
+
+	return chain, nil
+}
+
+// mergeTags returns the base tags with the extra tags added, where tags with
+// the same (case-insensitive) key are replaced.
+func mergeTags(base, extra []config.Tag) []config.Tag {
+	result := make([]config.Tag, 0, len(base)+len(extra))
+	for _, tag := range base {
+		replaced := false
+		for _, other := range extra {
+			if strings.EqualFold(tag.Key, other.Key) {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, tag)
+		}
+	}
+	return append(result, extra...)
+}
@@ -0,0 +1,148 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/joshdk/aws-auth/config"
+)
+
+func TestWithTags(t *testing.T) {
+	role := func(tags []config.Tag, transitiveTagKeys ...string) AssumeRoleTransform {
+		return AssumeRoleTransform{Profile: "admin", Role: &config.Role{
+			RoleARN:           "arn:aws:iam::000000000000:role/admin",
+			Tags:              tags,
+			TransitiveTagKeys: transitiveTagKeys,
+		}}
+	}
+	federate := func(tags []config.Tag) FederationTokenTransform {
+		return FederationTokenTransform{Profile: "federate", Federate: &config.Federate{
+			Tags: tags,
+		}}
+	}
+	session := SessionTokenTransform{Profile: "session", Session: &config.Session{}}
+
+	tests := []struct {
+		chain    []Transformer
+		extra    []config.Tag
+		expected []Transformer
+		err      bool
+	}{
+		{
+			chain:    []Transformer{session},
+			expected: []Transformer{session},
+		},
+		{
+			extra: []config.Tag{{Key: "team", Value: "a"}},
+			err:   true,
+		},
+		{
+			chain: []Transformer{session},
+			extra: []config.Tag{{Key: "team", Value: "a"}},
+			err:   true,
+		},
+		{
+			chain: []Transformer{
+				role([]config.Tag{{Key: "env", Value: "dev"}}),
+			},
+			extra: []config.Tag{{Key: "team", Value: "a"}},
+			expected: []Transformer{
+				role([]config.Tag{{Key: "env", Value: "dev"}, {Key: "team", Value: "a"}}),
+			},
+		},
+		{
+			chain: []Transformer{
+				role([]config.Tag{{Key: "Team", Value: "a"}, {Key: "env", Value: "dev"}}),
+			},
+			extra: []config.Tag{{Key: "team", Value: "b"}},
+			expected: []Transformer{
+				role([]config.Tag{{Key: "env", Value: "dev"}, {Key: "team", Value: "b"}}),
+			},
+		},
+		{
+			chain: []Transformer{
+				role([]config.Tag{{Key: "Team", Value: "a"}}, "Team"),
+			},
+			extra: []config.Tag{{Key: "team", Value: "b"}, {Key: "env", Value: "dev"}},
+			expected: []Transformer{
+				role([]config.Tag{{Key: "team", Value: "b"}, {Key: "env", Value: "dev"}}, "Team"),
+			},
+		},
+		{
+			chain: []Transformer{
+				session,
+				role(nil, "team"),
+			},
+			extra: []config.Tag{{Key: "env", Value: "dev"}},
+			err:   true,
+		},
+		{
+			chain: []Transformer{
+				role(nil),
+			},
+			extra: []config.Tag{{Key: "team", Value: "a"}, {Key: "Team", Value: "b"}},
+			err:   true,
+		},
+		{
+			chain: []Transformer{
+				role(nil),
+			},
+			extra: []config.Tag{{Key: "aws:team", Value: "a"}},
+			err:   true,
+		},
+		{
+			chain: []Transformer{
+				session,
+				federate([]config.Tag{{Key: "team", Value: "a"}}),
+			},
+			extra: []config.Tag{{Key: "TEAM", Value: "b"}},
+			expected: []Transformer{
+				session,
+				federate([]config.Tag{{Key: "TEAM", Value: "b"}}),
+			},
+		},
+	}
+
+	for index, test := range tests {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			// Keep a copy of the chain, to check that it is left untouched.
+			original := make([]Transformer, len(test.chain))
+			for index, transform := range test.chain {
+				switch transform := transform.(type) {
+				case AssumeRoleTransform:
+					role := *transform.Role
+					transform.Role = &role
+					original[index] = transform
+				case FederationTokenTransform:
+					federate := *transform.Federate
+					transform.Federate = &federate
+					original[index] = transform
+				default:
+					original[index] = transform
+				}
+			}
+
+			chain, err := WithTags(test.chain, test.extra)
+			switch {
+			case err != nil && !test.err:
+				t.Fatalf("expected no error but got error %q", err)
+			case err == nil && test.err:
+				t.Fatalf("expected an error but got no error")
+			case err != nil:
+				return
+			}
+
+			if !reflect.DeepEqual(chain, test.expected) {
+				t.Fatalf("expected chain %+v but got %+v", test.expected, chain)
+			}
+			if !reflect.DeepEqual(test.chain, original) {
+				t.Fatalf("expected chain %+v to be left untouched but got %+v", original, test.chain)
+			}
+		})
+	}
+}
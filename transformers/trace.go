@@ -0,0 +1,110 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/joshdk/aws-auth/config"
+)
+
+// Event describes a single transform that was made. It never contains any
+// secrets.
+type Event struct {
+	// Profile is the profile that the transform obtained credentials for.
+	Profile string
+
+	// API is the name of the STS API call, like "sts:AssumeRole".
+	API string
+
+	// Target is what the API call was made for, like a role ARN.
+	Target string
+
+	// Start is when the transform started.
+	Start time.Time
+
+	// Duration is how long the transform took, including any prompts.
+	Duration time.Duration
+
+	// ARN is the identity of the obtained credentials.
+	ARN string
+
+	// Expiration is when the obtained credentials expire.
+	Expiration time.Time
+
+	// Error is why the transform failed, if it did.
+	Error string
+}
+
+// Tracer is called with an Event after each transform is made. It may be
+// called concurrently.
+type Tracer func(Event)
+
+// tracerKey is the context.Context key for the Tracer.
+type tracerKey struct{}
+
+// WithTracer returns a copy of the given context.Context, such that any
+// transforms made with it are traced with the given Tracer.
+func WithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// transform makes the given transform, tracing it if the given
+// context.Context has a Tracer. The identity of the obtained credentials is
+// looked up for the trace, which is an additional API call.
+func transform(ctx context.Context, transformer Transformer, creds *sts.Credentials) (*sts.Credentials, error) {
+	tracer, ok := ctx.Value(tracerKey{}).(Tracer)
+	if !ok {
+		return transformer.Transform(ctx, creds)
+	}
+
+	api, target, endpoint := describe(transformer)
+	event := Event{
+		Profile: profileOf(transformer),
+		API:     api,
+		Target:  target,
+		Start:   time.Now(),
+	}
+
+	newCreds, err := transformer.Transform(ctx, creds)
+	event.Duration = time.Since(event.Start)
+
+	if err != nil {
+		event.Error = err.Error()
+		tracer(event)
+		return nil, err
+	}
+
+	event.Expiration = aws.TimeValue(newCreds.Expiration)
+
+	// The identity is only informative, so a failed lookup is not an error.
+	if identity, err := Enrich(ctx, newCreds, endpoint); err == nil {
+		event.ARN = identity.ARN
+	}
+
+	tracer(event)
+	return newCreds, nil
+}
+
+// describe returns the API call that the given Transformer makes, what it is
+// made for, and the endpoint that it is made against.
+func describe(transformer Transformer) (string, string, config.Endpoint) {
+	switch transform := transformer.(type) {
+	case AssumeRoleTransform:
+		return "sts:AssumeRole", transform.Role.RoleARN, transform.Role.Endpoint
+	case SessionTokenTransform:
+		return "sts:GetSessionToken", transform.Session.MFASerial, transform.Session.Endpoint
+	case FederationTokenTransform:
+		return "sts:GetFederationToken", valueOr(transform.Federate.Name, defaultFederationName), transform.Federate.Endpoint
+	case SAMLTransform:
+		return "sts:AssumeRoleWithSAML", transform.SAML.RoleARN, transform.SAML.Endpoint
+	default:
+		return fmt.Sprintf("%T", transformer), "", config.Endpoint{}
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+
+package transformers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// failingTransform fails with the given error.
+type failingTransform struct {
+	err error
+}
+
+func (f failingTransform) Transform(context.Context, *sts.Credentials) (*sts.Credentials, error) {
+	return nil, f.err
+}
+
+func TestTraceFailure(t *testing.T) {
+	transform := failingTransform{
+		err: errors.New("AccessDenied"),
+	}
+
+	var events []Event
+	ctx := WithTracer(context.Background(), func(event Event) {
+		events = append(events, event)
+	})
+
+	if _, err := Transform(ctx, nil, []Transformer{transform}); err == nil {
+		t.Fatalf("expected an error but got no error")
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event but got %d", len(events))
+	}
+
+	if event := events[0]; event.API != "transformers.failingTransform" || event.Error != "AccessDenied" || event.ARN != "" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
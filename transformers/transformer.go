@@ -5,25 +5,57 @@
 package transformers
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/aws/aws-sdk-go/service/sts"
 )
 
 // Transformer represents types that are able to trade a given sts.Credentials
-// value for a new (derived) sts.Credentials value.
+// value for a new (derived) sts.Credentials value. Any API calls or prompts
+// are abandoned if the given context.Context is canceled.
 type Transformer interface {
-	Transform(*sts.Credentials) (*sts.Credentials, error)
+	Transform(context.Context, *sts.Credentials) (*sts.Credentials, error)
 }
 
 // Transform is a reduce-style operation. The given sts.Credentials are passed
 // to the first Transformer, the result of which is passed to the second, and
-// so on.
-func Transform(credentials *sts.Credentials, transformers []Transformer) (*sts.Credentials, error) {
+// so on. No further transforms are made once the given context.Context is
+// canceled.
+func Transform(ctx context.Context, credentials *sts.Credentials, transformers []Transformer) (*sts.Credentials, error) {
 	for _, transformer := range transformers {
-		newCredentials, err := transformer.Transform(credentials)
+		if err := ctx.Err(); err != nil {
+			return nil, chainError{
+				profile: profileOf(transformer),
+				err:     classify(err),
+			}
+		}
+
+		newCredentials, err := transform(ctx, transformer, credentials)
 		if err != nil {
-			return nil, err
+			return nil, chainError{
+				profile: profileOf(transformer),
+				err:     classify(err),
+			}
 		}
 		credentials = newCredentials
 	}
 	return credentials, nil
 }
+
+// profileOf returns the name of the profile that the given Transformer
+// obtains credentials for.
+func profileOf(transformer Transformer) string {
+	switch transform := transformer.(type) {
+	case AssumeRoleTransform:
+		return transform.Profile
+	case SessionTokenTransform:
+		return transform.Profile
+	case FederationTokenTransform:
+		return transform.Profile
+	case SAMLTransform:
+		return transform.Profile
+	default:
+		return fmt.Sprintf("%T", transformer)
+	}
+}